@@ -8,6 +8,7 @@ import (
 
 	"github.com/urfave/cli/v3"
 
+	"gh.tarampamp.am/indocker-app/app/internal/cli/diagnose"
 	"gh.tarampamp.am/indocker-app/app/internal/cli/start"
 	"gh.tarampamp.am/indocker-app/app/internal/logger"
 	"gh.tarampamp.am/indocker-app/app/internal/version"
@@ -75,6 +76,7 @@ func NewApp() *cli.Command {
 		},
 		Commands: []*cli.Command{
 			start.NewCommand(log),
+			diagnose.NewCommand(),
 		},
 		Version: fmt.Sprintf("%s (%s)", version.Version(), runtime.Version()),
 		Flags: []cli.Flag{ // global flags
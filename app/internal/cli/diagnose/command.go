@@ -0,0 +1,153 @@
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/cli/shared"
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routesfile"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+// NewCommand creates `diagnose` command.
+func NewCommand() *cli.Command {
+	var (
+		dockerHostFlag     = shared.DockerHostFlag
+		conflictPolicyFlag = shared.RouteConflictPolicyFlag
+		labelPrefixFlag    = shared.LabelPrefixFlag
+		strictLabelsFlag   = shared.StrictLabelsFlag
+		virtualHostEnvFlag = shared.VirtualHostEnvFlag
+		traefikLabelsFlag  = shared.TraefikLabelsFlag
+		netDomainsFlag     = shared.IgnoreNetworkDomainsFlag
+		routesFileFlag     = shared.RoutesFileFlag
+	)
+
+	return &cli.Command{
+		Name:      "diagnose",
+		Aliases:   []string{"why"},
+		Usage:     "Explain why the container is (or is not) routed",
+		ArgsUsage: "<container ID or name>",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			var ref = strings.TrimSpace(c.Args().First())
+			if ref == "" {
+				return errors.New("missing container ID or name")
+			}
+
+			dc, dcErr := client.NewClientWithOpts(client.WithHost(c.String(dockerHostFlag.Name)))
+			if dcErr != nil {
+				return fmt.Errorf("failed to create docker client: %w", dcErr)
+			}
+
+			defer func() { _ = dc.Close() }()
+
+			var policy, _ = docker.ParseConflictPolicy(c.String(conflictPolicyFlag.Name)) // validated by the flag
+
+			var state = docker.NewState(dc,
+				docker.WithConflictPolicy(policy),
+				docker.WithLabelPrefix(c.String(labelPrefixFlag.Name)),
+				docker.WithStrictLabels(c.Bool(strictLabelsFlag.Name)),
+				docker.WithVirtualHostEnv(c.Bool(virtualHostEnvFlag.Name)),
+				docker.WithTraefikLabels(c.Bool(traefikLabelsFlag.Name)),
+				docker.WithNetworkDomains(!c.Bool(netDomainsFlag.Name)),
+			)
+
+			// the state is required to detect the hostname conflicts
+			if err := state.Update(ctx); err != nil {
+				return fmt.Errorf("failed to update docker state: %w", err)
+			}
+
+			diagnosis, err := state.Diagnose(ctx, ref)
+			if err != nil {
+				return err
+			}
+
+			// static routes may override the discovered ones
+			if path := c.String(routesFileFlag.Name); path != "" {
+				if err = explainOverride(ctx, state, path, diagnosis); err != nil {
+					return err
+				}
+			}
+
+			var out io.Writer = os.Stdout
+
+			if c.Root().Writer != nil {
+				out = c.Root().Writer
+			}
+
+			Print(out, diagnosis)
+
+			if !diagnosis.Routed {
+				return cli.Exit("", 1)
+			}
+
+			return nil
+		},
+		Flags: []cli.Flag{
+			&dockerHostFlag,
+			&conflictPolicyFlag,
+			&labelPrefixFlag,
+			&strictLabelsFlag,
+			&virtualHostEnvFlag,
+			&traefikLabelsFlag,
+			&netDomainsFlag,
+			&routesFileFlag,
+		},
+	}
+}
+
+// explainOverride checks if the hostname of the routed container is overridden by the static routes file.
+func explainOverride(ctx context.Context, state *docker.State, path string, d *docker.Diagnosis) error {
+	routes, err := routesfile.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load the routes file: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var static = routing.NewMemoryProvider(string(docker.RouteSourceFile))
+
+	for _, r := range routes {
+		static.Set(r)
+	}
+
+	var router = routing.NewAggregator(zap.NewNop()).
+		Add(routing.NewDockerProvider(state), 0).
+		Add(static, 1)
+
+	go func() { _ = router.Run(ctx) }()
+
+	select {
+	case <-router.Ready():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	routing.ExplainOverride(d, router)
+
+	return nil
+}
+
+// Print writes the human-readable diagnosis to the writer.
+func Print(w io.Writer, d *docker.Diagnosis) {
+	_, _ = fmt.Fprintf(w, "Container: %s (%s)\n\n", d.ContainerName, d.ContainerID)
+
+	for _, step := range d.Steps {
+		_, _ = fmt.Fprintf(w, "[%-5s] %s: %s\n", step.Level, step.Name, step.Message)
+	}
+
+	if d.Routed {
+		_, _ = fmt.Fprintf(w, "\nThe container is routed: https://%s.indocker.app -> %s\n", d.Hostname, d.URL)
+	} else {
+		_, _ = fmt.Fprintln(w, "\nThe container is NOT routed")
+	}
+}
@@ -0,0 +1,57 @@
+package diagnose_test
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gh.tarampamp.am/indocker-app/app/internal/cli/diagnose"
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+func TestPrint(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveDiagnosis *docker.Diagnosis
+		wantOutput    string
+	}{
+		"routed": {
+			giveDiagnosis: &docker.Diagnosis{
+				ContainerID:   "abc",
+				ContainerName: "foo",
+				Routed:        true,
+				Hostname:      "foo",
+				URL:           &url.URL{Scheme: "http", Host: "172.17.0.2:80"},
+				Steps: []docker.DiagnosticStep{
+					{Name: "host label", Level: docker.DiagnosticOK, Message: "found"},
+					{Name: "port label", Level: docker.DiagnosticWarn, Message: "not set"},
+				},
+			},
+			wantOutput: "Container: foo (abc)\n\n" +
+				"[ok   ] host label: found\n" +
+				"[warn ] port label: not set\n" +
+				"\nThe container is routed: https://foo.indocker.app -> http://172.17.0.2:80\n",
+		},
+		"not routed": {
+			giveDiagnosis: &docker.Diagnosis{
+				ContainerID:   "abc",
+				ContainerName: "foo",
+				Steps:         []docker.DiagnosticStep{{Name: "host label", Level: docker.DiagnosticError, Message: "x"}},
+			},
+			wantOutput: "Container: foo (abc)\n\n[error] host label: x\n\nThe container is NOT routed\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			diagnose.Print(&buf, tt.giveDiagnosis)
+
+			assert.Equal(t, tt.wantOutput, buf.String())
+		})
+	}
+}
@@ -3,11 +3,17 @@ package shared
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/client"
 	"github.com/urfave/cli/v3"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
 )
 
 const httpCategory = "HTTP"
@@ -51,6 +57,28 @@ var (
 		OnlyOnce:  true,
 		Validator: validateTCPPortNumber,
 	}
+	Http3Flag = cli.BoolFlag{
+		Name:     "http3",
+		Category: httpCategory,
+		Usage:    "enable the HTTP/3 (QUIC) server on the HTTPS port (UDP), advertised using the Alt-Svc header",
+		Sources:  cli.EnvVars("HTTP3"),
+		OnlyOnce: true,
+	}
+	Http3AdvertisedPortFlag = cli.UintFlag{
+		Name:     "http3-advertised-port",
+		Category: httpCategory,
+		Usage: "the HTTP/3 port, advertised in the Alt-Svc header (e.g. 443, if the HTTPS port is published " +
+			"from the container; 0 = the HTTPS port)",
+		Sources:  cli.EnvVars("HTTP3_ADVERTISED_PORT"),
+		OnlyOnce: true,
+		Validator: func(port uint) error {
+			if port == 0 {
+				return nil
+			}
+
+			return validateTCPPortNumber(port)
+		},
+	}
 	ReadTimeoutFlag = cli.DurationFlag{
 		Name:      "read-timeout",
 		Category:  httpCategory,
@@ -122,9 +150,200 @@ var (
 			return nil
 		},
 	}
+	RouteConflictPolicyFlag = cli.StringFlag{
+		Name:     "route-conflict-policy",
+		Category: dockerCategory,
+		Usage: "how to route the hostname, claimed by containers from different projects or images " +
+			"(merge/newest/priority/reject)",
+		Value:    string(docker.ConflictPolicyMerge),
+		Sources:  cli.EnvVars("ROUTE_CONFLICT_POLICY"),
+		OnlyOnce: true,
+		Config:   cli.StringConfig{TrimSpace: true},
+		Validator: func(s string) error {
+			_, err := docker.ParseConflictPolicy(s)
+
+			return err
+		},
+	}
+	LabelPrefixFlag = cli.StringFlag{
+		Name:     "label-prefix",
+		Category: dockerCategory,
+		Usage: "prefix (namespace) of the routing labels, e.g. PREFIX.host (use different prefixes to run " +
+			"a few instances on the same docker daemon)",
+		Value:    docker.DefaultLabelPrefix,
+		Sources:  cli.EnvVars("LABEL_PREFIX"),
+		OnlyOnce: true,
+		Config:   cli.StringConfig{TrimSpace: true},
+		Validator: func(s string) error {
+			if s == "" {
+				return fmt.Errorf("missing label prefix")
+			}
+
+			if strings.ContainsFunc(s, func(r rune) bool {
+				return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '.' && r != '-' && r != '_'
+			}) {
+				return fmt.Errorf("invalid label prefix %q (only lowercase letters, digits, dots, dashes and "+
+					"underscores are allowed)", s)
+			}
+
+			return nil
+		},
+	}
+	StrictLabelsFlag = cli.BoolFlag{
+		Name:     "strict-labels",
+		Category: dockerCategory,
+		Usage:    "honor only the prefixed routing labels (ignore the bare host, port, scheme, network, etc.)",
+		Sources:  cli.EnvVars("STRICT_LABELS"),
+		OnlyOnce: true,
+	}
+	VirtualHostEnvFlag = cli.BoolFlag{
+		Name:     "virtual-host-env",
+		Category: dockerCategory,
+		Usage:    "route the containers without labels using the nginx-proxy VIRTUAL_HOST/PORT/PROTO/PATH env variables",
+		Sources:  cli.EnvVars("VIRTUAL_HOST_ENV"),
+		OnlyOnce: true,
+	}
+	TraefikLabelsFlag = cli.BoolFlag{
+		Name:     "traefik-labels",
+		Category: dockerCategory,
+		Usage: "route the containers without labels using the Traefik labels " +
+			"(Host/PathPrefix rules, ports, schemes, etc.)",
+		Sources:  cli.EnvVars("TRAEFIK_LABELS"),
+		OnlyOnce: true,
+	}
+	IgnoreNetworkDomainsFlag = cli.BoolFlag{
+		Name:     "ignore-network-domains",
+		Category: dockerCategory,
+		Usage: "do not route the containers, attached to the networks with the domain label (PREFIX.domain), " +
+			"as SERVICE.DOMAIN",
+		Sources:  cli.EnvVars("IGNORE_NETWORK_DOMAINS"),
+		OnlyOnce: true,
+	}
 )
 
 var (
+	RoutesFileFlag = cli.StringFlag{
+		Name:      "routes-file",
+		Usage:     "path to the YAML/TOML/JSON file with static routes (watched for changes, optional)",
+		Sources:   cli.EnvVars("ROUTES_FILE"),
+		OnlyOnce:  true,
+		Config:    cli.StringConfig{TrimSpace: true},
+		Validator: validateFilePath("routes file", true),
+	}
+	RoutesURLFlag = cli.StringFlag{
+		Name:     "routes-url",
+		Usage:    "URL of the HTTP endpoint, returning the JSON document with routes (polled periodically, optional)",
+		Sources:  cli.EnvVars("ROUTES_URL"),
+		OnlyOnce: true,
+		Config:   cli.StringConfig{TrimSpace: true},
+		Validator: func(s string) error {
+			if s == "" {
+				return nil
+			}
+
+			if u, err := url.Parse(s); err != nil {
+				return fmt.Errorf("wrong routes URL: %w", err)
+			} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("wrong routes URL (%s): HTTP(s) URL expected", s)
+			}
+
+			return nil
+		},
+	}
+	RoutesPollIntervalFlag = cli.DurationFlag{
+		Name:      "routes-poll-interval",
+		Usage:     "how often to poll the routes URL",
+		Value:     time.Second * 30,
+		Sources:   cli.EnvVars("ROUTES_POLL_INTERVAL"),
+		OnlyOnce:  true,
+		Validator: validateDuration("routes poll interval", time.Second, time.Hour),
+	}
+	RoutesStateFileFlag = cli.StringFlag{
+		Name:     "routes-state-file",
+		Usage:    "path to the file, where the routes added using the API are persisted (optional)",
+		Sources:  cli.EnvVars("ROUTES_STATE_FILE"),
+		OnlyOnce: true,
+		Config:   cli.StringConfig{TrimSpace: true},
+		Validator: func(s string) error {
+			if s == "" {
+				return nil
+			}
+
+			if stat, err := os.Stat(filepath.Dir(s)); err != nil {
+				return fmt.Errorf("failed to find the routes state file directory (%s): %w", s, err)
+			} else if !stat.IsDir() {
+				return fmt.Errorf("wrong routes state file path (%s)", s)
+			}
+
+			return nil
+		},
+	}
+	HostPortsFlag = cli.StringFlag{
+		Name: "host-ports",
+		Usage: "allowed host port ranges for the PORT.indocker.app shortcut, routed to the host " +
+			"(e.g. 3000-3999,5173; empty = disabled)",
+		Sources:  cli.EnvVars("HOST_PORTS"),
+		OnlyOnce: true,
+		Config:   cli.StringConfig{TrimSpace: true},
+		Validator: func(s string) error {
+			_, err := routing.ParsePortRanges(s)
+
+			return err
+		},
+	}
+	HostPortsAddressFlag = cli.StringFlag{
+		Name:     "host-ports-address",
+		Usage:    "host address for the port shortcut (empty = the docker host gateway, or 127.0.0.1 outside docker)",
+		Sources:  cli.EnvVars("HOST_PORTS_ADDRESS"),
+		OnlyOnce: true,
+		Config:   cli.StringConfig{TrimSpace: true},
+	}
+	HostPortsNamedFlag = cli.BoolFlag{
+		Name:     "host-ports-named",
+		Usage:    "allow the NAME-PORT.indocker.app form of the host port shortcut",
+		Sources:  cli.EnvVars("HOST_PORTS_NAMED"),
+		OnlyOnce: true,
+	}
+	ForwardAddressFlag = cli.StringFlag{
+		Name: "forward-address",
+		Usage: "IP address to listen on for the TCP/UDP port forwardings, set using the container labels (use 0.0.0.0 " +
+			"inside docker, and publish the ports; empty = disabled)",
+		Value:    "127.0.0.1",
+		Sources:  cli.EnvVars("FORWARD_ADDRESS"),
+		OnlyOnce: true,
+		Config:   cli.StringConfig{TrimSpace: true},
+		Validator: func(ip string) error {
+			if ip != "" && net.ParseIP(ip) == nil {
+				return fmt.Errorf("wrong IP address [%s] for the port forwardings", ip)
+			}
+
+			return nil
+		},
+	}
+	RouteGracePeriodFlag = cli.DurationFlag{
+		Name: "route-grace-period",
+		Usage: "how long to hold the requests to the recently removed routes (e.g. the container is being " +
+			"recreated), waiting for the route to come back (0 to disable)",
+		Value:     time.Second * 10,
+		Sources:   cli.EnvVars("ROUTE_GRACE_PERIOD"),
+		OnlyOnce:  true,
+		Validator: validateDuration("route grace period", 0, time.Minute*5),
+	}
+	ErrorTemplatesDirFlag = cli.StringFlag{
+		Name: "error-templates-dir",
+		Usage: "path to the directory with the error page templates, named by the status code or class " +
+			"(404.html, 5xx.html, error.html; optional)",
+		Sources:   cli.EnvVars("ERROR_TEMPLATES_DIR"),
+		OnlyOnce:  true,
+		Config:    cli.StringConfig{TrimSpace: true},
+		Validator: validateDirPath("error templates directory", true),
+	}
+	ErrorHideHostsFlag = cli.BoolFlag{
+		Name:     "error-hide-hosts",
+		Usage:    "do not list the registered hosts on the error pages (the \"did you mean\" suggestions are still shown)",
+		Sources:  cli.EnvVars("ERROR_HIDE_HOSTS"),
+		OnlyOnce: true,
+	}
 	ShutdownTimeoutFlag = cli.DurationFlag{
 		Name:      "shutdown-timeout",
 		Usage:     "maximum duration for graceful shutdown",
@@ -160,6 +379,26 @@ func validateDuration(name string, minValue, maxValue time.Duration) func(d time
 	}
 }
 
+func validateDirPath(name string, isOptional bool) func(s string) error {
+	return func(s string) error {
+		if isOptional && s == "" {
+			return nil
+		}
+
+		if s == "" {
+			return fmt.Errorf("missing %s", name)
+		}
+
+		if stat, err := os.Stat(s); err != nil {
+			return fmt.Errorf("failed to find %s (%s): %w", name, s, err)
+		} else if !stat.IsDir() {
+			return fmt.Errorf("%s is not a directory", name)
+		}
+
+		return nil
+	}
+}
+
 func validateFilePath(name string, isOptional bool) func(s string) error {
 	return func(s string) error {
 		if isOptional && s == "" {
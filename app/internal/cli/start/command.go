@@ -19,7 +19,13 @@ import (
 	"gh.tarampamp.am/indocker-app/app/internal/cli/shared"
 	"gh.tarampamp.am/indocker-app/app/internal/cli/start/healthcheck"
 	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/forward"
 	appHttp "gh.tarampamp.am/indocker-app/app/internal/http"
+	"gh.tarampamp.am/indocker-app/app/internal/http/passthrough"
+	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
+	"gh.tarampamp.am/indocker-app/app/internal/routesfile"
+	"gh.tarampamp.am/indocker-app/app/internal/routestore"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
 )
 
 type (
@@ -35,16 +41,48 @@ type (
 				tcpPort uint16           // TCP port number for HTTPS server
 				cert    *tls.Certificate // TLS certificate to use
 			}
+			http3 struct {
+				enabled        bool   // serve HTTP/3 (QUIC) on the HTTPS port (UDP)
+				advertisedPort uint16 // the port, advertised in the Alt-Svc header (0 = the HTTPS port)
+			}
 			timeouts struct {
 				httpRead, httpWrite, httpIdle time.Duration // timeouts for HTTP(s) servers
 				shutdown                      time.Duration // maximum amount of time to wait for the server to stop
+				routeGrace                    time.Duration // how long to hold the requests to the removed routes
 			}
 			docker struct {
-				host string // Docker daemon host (e.g. "unix:///var/run/docker.sock")
+				host           string                // Docker daemon host (e.g. "unix:///var/run/docker.sock")
+				conflictPolicy docker.ConflictPolicy // how to resolve the route conflicts
+				labelPrefix    string                // the routing labels prefix (namespace)
+				strictLabels   bool                  // honor only the prefixed routing labels
+				virtualHosts   bool                  // honor the nginx-proxy VIRTUAL_* env variables
+				traefikLabels  bool                  // honor the Traefik labels
+				netDomains     bool                  // honor the domain labels of the networks
+			}
+			routes struct {
+				file         string        // path to the static routes file (optional)
+				url          string        // URL of the remote routes endpoint (optional)
+				pollInterval time.Duration // how often to poll the remote routes endpoint
+				stateFile    string        // path to the runtime (API) routes state file (optional)
+			}
+			hostPorts struct {
+				ranges  routing.PortRanges // allowed host ports (empty = disabled)
+				address string             // host address (empty = auto)
+				named   bool               // allow the "<name>-<port>" hostnames
+			}
+			forward struct {
+				address string // the address to listen on for the port forwardings (empty = disabled)
+			}
+			errorPages struct {
+				templatesDir string // path to the directory with the error page templates (optional)
+				hideHosts    bool   // do not list the registered hosts on the error pages
 			}
 			frontend struct {
 				useLive bool // false to use embedded frontend, true to use live (local)
 			}
+			api struct {
+				readOnly bool // disable the API methods that change something (e.g. container actions)
+			}
 		}
 	}
 )
@@ -58,16 +96,41 @@ func NewCommand(log *zap.Logger) *cli.Command { //nolint:funlen
 		httpsPortFlag       = shared.HttpsPortFlag
 		httpsCertFileFlag   = shared.HttpsCertFileFlag
 		httpsKeyFileFlag    = shared.HttpsKeyFileFlag
+		http3Flag           = shared.Http3Flag
+		http3PortFlag       = shared.Http3AdvertisedPortFlag
 		readTimeoutFlag     = shared.ReadTimeoutFlag
 		writeTimeoutFlag    = shared.WriteTimeoutFlag
 		idleTimeoutFlag     = shared.IdleTimeoutFlag
 		shutdownTimeoutFlag = shared.ShutdownTimeoutFlag
+		routeGraceFlag      = shared.RouteGracePeriodFlag
+		errorTemplatesFlag  = shared.ErrorTemplatesDirFlag
+		errorHideHostsFlag  = shared.ErrorHideHostsFlag
 		dockerHostFlag      = shared.DockerHostFlag
+		conflictPolicyFlag  = shared.RouteConflictPolicyFlag
+		labelPrefixFlag     = shared.LabelPrefixFlag
+		strictLabelsFlag    = shared.StrictLabelsFlag
+		virtualHostEnvFlag  = shared.VirtualHostEnvFlag
+		traefikLabelsFlag   = shared.TraefikLabelsFlag
+		netDomainsFlag      = shared.IgnoreNetworkDomainsFlag
+		routesFileFlag      = shared.RoutesFileFlag
+		routesURLFlag       = shared.RoutesURLFlag
+		routesPollFlag      = shared.RoutesPollIntervalFlag
+		routesStateFileFlag = shared.RoutesStateFileFlag
+		hostPortsFlag       = shared.HostPortsFlag
+		hostPortsAddrFlag   = shared.HostPortsAddressFlag
+		hostPortsNamedFlag  = shared.HostPortsNamedFlag
+		forwardAddrFlag     = shared.ForwardAddressFlag
 		useLiveFrontendFlag = cli.BoolFlag{
 			Name:     "use-live-frontend",
 			Usage:    "use frontend from the local directory instead of the embedded one (useful for development)",
 			OnlyOnce: true,
 		}
+		readOnlyAPIFlag = cli.BoolFlag{
+			Name:     "read-only-api",
+			Usage:    "disable the monitor API methods that change something (e.g. start/stop containers)",
+			Sources:  cli.EnvVars("READ_ONLY_API"),
+			OnlyOnce: true,
+		}
 	)
 
 	cmd.c = &cli.Command{
@@ -81,12 +144,32 @@ func NewCommand(log *zap.Logger) *cli.Command { //nolint:funlen
 			opt.addr = c.String(addrFlag.Name)
 			opt.http.tcpPort = uint16(c.Uint(httpPortFlag.Name))   //nolint:gosec
 			opt.https.tcpPort = uint16(c.Uint(httpsPortFlag.Name)) //nolint:gosec
+			opt.http3.enabled = c.Bool(http3Flag.Name)
+			opt.http3.advertisedPort = uint16(c.Uint(http3PortFlag.Name)) //nolint:gosec
 			opt.timeouts.httpRead = c.Duration(readTimeoutFlag.Name)
 			opt.timeouts.httpWrite = c.Duration(writeTimeoutFlag.Name)
 			opt.timeouts.httpIdle = c.Duration(idleTimeoutFlag.Name)
 			opt.timeouts.shutdown = c.Duration(shutdownTimeoutFlag.Name)
+			opt.timeouts.routeGrace = c.Duration(routeGraceFlag.Name)
 			opt.docker.host = c.String(dockerHostFlag.Name)
+			opt.docker.conflictPolicy, _ = docker.ParseConflictPolicy(c.String(conflictPolicyFlag.Name)) // validated
+			opt.docker.labelPrefix = c.String(labelPrefixFlag.Name)
+			opt.docker.strictLabels = c.Bool(strictLabelsFlag.Name)
+			opt.docker.virtualHosts = c.Bool(virtualHostEnvFlag.Name)
+			opt.docker.traefikLabels = c.Bool(traefikLabelsFlag.Name)
+			opt.docker.netDomains = !c.Bool(netDomainsFlag.Name)
+			opt.routes.file = c.String(routesFileFlag.Name)
+			opt.routes.url = c.String(routesURLFlag.Name)
+			opt.routes.pollInterval = c.Duration(routesPollFlag.Name)
+			opt.routes.stateFile = c.String(routesStateFileFlag.Name)
+			opt.hostPorts.ranges, _ = routing.ParsePortRanges(c.String(hostPortsFlag.Name)) // validated
+			opt.hostPorts.address = c.String(hostPortsAddrFlag.Name)
+			opt.hostPorts.named = c.Bool(hostPortsNamedFlag.Name)
+			opt.forward.address = c.String(forwardAddrFlag.Name)
+			opt.errorPages.templatesDir = c.String(errorTemplatesFlag.Name)
+			opt.errorPages.hideHosts = c.Bool(errorHideHostsFlag.Name)
 			opt.frontend.useLive = c.Bool(useLiveFrontendFlag.Name)
+			opt.api.readOnly = c.Bool(readOnlyAPIFlag.Name)
 
 			// if user provided both certificate and key files, use them
 			if crt, key := c.String(httpsCertFileFlag.Name), c.String(httpsKeyFileFlag.Name); crt != "" && key != "" { //nolint:nestif,lll
@@ -125,12 +208,32 @@ func NewCommand(log *zap.Logger) *cli.Command { //nolint:funlen
 			&httpsPortFlag,
 			&httpsCertFileFlag,
 			&httpsKeyFileFlag,
+			&http3Flag,
+			&http3PortFlag,
 			&readTimeoutFlag,
 			&writeTimeoutFlag,
 			&idleTimeoutFlag,
 			&shutdownTimeoutFlag,
+			&routeGraceFlag,
 			&dockerHostFlag,
+			&conflictPolicyFlag,
+			&labelPrefixFlag,
+			&strictLabelsFlag,
+			&virtualHostEnvFlag,
+			&traefikLabelsFlag,
+			&netDomainsFlag,
+			&routesFileFlag,
+			&routesURLFlag,
+			&routesPollFlag,
+			&routesStateFileFlag,
+			&hostPortsFlag,
+			&hostPortsAddrFlag,
+			&hostPortsNamedFlag,
+			&forwardAddrFlag,
+			&errorTemplatesFlag,
+			&errorHideHostsFlag,
 			&useLiveFrontendFlag,
+			&readOnlyAPIFlag,
 		},
 		Commands: []*cli.Command{
 			healthcheck.NewCommand(),
@@ -145,6 +248,19 @@ func (cmd *command) Run(parentCtx context.Context, log *zap.Logger) error { //no
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 
+	// load the user-defined error page templates, if any
+	var errorTemplates proxy.ErrorTemplates
+
+	if dir := cmd.options.errorPages.templatesDir; dir != "" {
+		var tplErr error
+
+		if errorTemplates, tplErr = proxy.LoadErrorTemplates(dir); tplErr != nil {
+			return tplErr
+		}
+
+		log.Info("Error page templates loaded", zap.String("dir", dir), zap.Int("count", len(errorTemplates)))
+	}
+
 	// create Docker client
 	dc, dcClose, dcErr := cmd.makeDockerClient(ctx, log.Named("docker"))
 	if dcErr != nil {
@@ -161,15 +277,64 @@ func (cmd *command) Run(parentCtx context.Context, log *zap.Logger) error { //no
 		defer stateClose()
 	}
 
-	// create HTTP server
-	var server = appHttp.NewServer(ctx, log,
+	// create the runtime routes store (the routes are managed using the API)
+	runtimeRoutes, runtimeRoutesErr := routestore.New(log.Named("routes.api"),
+		routestore.WithStateFile(cmd.options.routes.stateFile),
+	)
+	if runtimeRoutesErr != nil {
+		return runtimeRoutesErr
+	}
+
+	// create the routing table, aggregated from all route providers
+	aggregator, aggregatorErr := cmd.makeRouter(ctx, log.Named("routing"), dockerState, runtimeRoutes, cancel)
+	if aggregatorErr != nil {
+		return aggregatorErr
+	}
+
+	// forward the raw TCP and UDP ports to the containers (set using the labels like "indocker.tcp.5432=listen:15432")
+	if address := cmd.options.forward.address; address != "" {
+		go func() {
+			if err := forward.New(log.Named("forward"), dockerState, address).Run(ctx); err != nil {
+				log.Error("Port forwarding failed", zap.Error(err))
+			}
+		}()
+	}
+
+	var router routing.Router = aggregator
+
+	// route the "<port>.indocker.app" hostnames to the host, if enabled
+	if ranges := cmd.options.hostPorts.ranges; len(ranges) > 0 {
+		var address = cmd.hostPortsAddress(log)
+
+		router = routing.NewHostPorts(aggregator, address, ranges,
+			routing.WithNamedHostPorts(cmd.options.hostPorts.named),
+		)
+
+		log.Info("Host port shortcut enabled", zap.String("address", address), zap.Stringer("ports", ranges))
+	}
+
+	var serverOpts = []appHttp.ServerOption{
 		appHttp.WithReadTimeout(cmd.options.timeouts.httpRead),
 		appHttp.WithWriteTimeout(cmd.options.timeouts.httpWrite),
 		appHttp.WithIDLETimeout(cmd.options.timeouts.httpIdle),
-	).Register(
+		appHttp.WithReadOnlyAPI(cmd.options.api.readOnly),
+		appHttp.WithRouteGracePeriod(cmd.options.timeouts.routeGrace),
+		appHttp.WithErrorTemplates(errorTemplates),
+		appHttp.WithHiddenHosts(cmd.options.errorPages.hideHosts),
+	}
+
+	if cmd.options.http3.enabled {
+		serverOpts = append(serverOpts, appHttp.WithHTTP3(cmd.options.http3.advertisedPort))
+	}
+
+	// create HTTP server
+	var server = appHttp.NewServer(ctx, log, serverOpts...).Register(
 		ctx,
 		log,
+		router,
 		dockerState,
+		runtimeRoutes,
+		dc,
 		cmd.options.frontend.useLive,
 	)
 
@@ -187,6 +352,20 @@ func (cmd *command) Run(parentCtx context.Context, log *zap.Logger) error { //no
 		return fmt.Errorf("HTTPS port error (%s:%d): %w", cmd.options.addr, cmd.options.https.tcpPort, httpsLnErr)
 	}
 
+	// pass the raw TLS connections to the containers with the TLS passthrough label (SNI-based)
+	httpsLn = passthrough.New(httpsLn, log.Named("passthrough"), router)
+
+	// open HTTP/3 (UDP) port, if enabled
+	var http3Conn net.PacketConn
+
+	if cmd.options.http3.enabled {
+		var http3ConnErr error
+
+		if http3Conn, http3ConnErr = net.ListenPacket("udp", httpsLn.Addr().String()); http3ConnErr != nil {
+			return fmt.Errorf("HTTP/3 port error (%s): %w", httpsLn.Addr(), http3ConnErr)
+		}
+	}
+
 	// try to determine if the app is running inside a Docker container
 	var iAmInsideDocker = cmd.isInsideDocker()
 
@@ -249,6 +428,26 @@ func (cmd *command) Run(parentCtx context.Context, log *zap.Logger) error { //no
 		}
 	}()
 
+	// start HTTP/3 server in separate goroutine, if enabled
+	if http3Conn != nil {
+		go func() {
+			defer func() { _ = http3Conn.Close() }()
+
+			log.Info("HTTP/3 server starting",
+				zap.String("address", http3Conn.LocalAddr().String()),
+				zap.Uint16("advertised port", cmd.options.http3.advertisedPort),
+			)
+
+			if err := server.StartHTTP3(ctx, http3Conn, *cmd.options.https.cert); err != nil {
+				cancel() // cancel the context on error (this is critical for us)
+
+				log.Error("Failed to start HTTP/3 server", zap.Error(err))
+			} else {
+				log.Debug("HTTP/3 server stopped")
+			}
+		}()
+	}
+
 	// here, we are blocking until the context is canceled. this will occur when the user sends a signal to stop
 	// the app by pressing Ctrl+C, terminating the process, or if the HTTP/HTTPS server fails to start
 	<-ctx.Done()
@@ -281,7 +480,15 @@ func (cmd *command) makeDockerStateWatcher(
 	log *zap.Logger,
 	dc *client.Client,
 ) (*docker.State, func(), error) {
-	var state = docker.NewState(dc)
+	var state = docker.NewState(dc,
+		docker.WithStateLogger(log),
+		docker.WithConflictPolicy(cmd.options.docker.conflictPolicy),
+		docker.WithLabelPrefix(cmd.options.docker.labelPrefix),
+		docker.WithStrictLabels(cmd.options.docker.strictLabels),
+		docker.WithVirtualHostEnv(cmd.options.docker.virtualHosts),
+		docker.WithTraefikLabels(cmd.options.docker.traefikLabels),
+		docker.WithNetworkDomains(cmd.options.docker.netDomains),
+	)
 
 	if err := state.Update(ctx); err != nil { // initial update
 		return nil, func() {}, fmt.Errorf("failed to update docker state: %w", err)
@@ -290,6 +497,7 @@ func (cmd *command) makeDockerStateWatcher(
 	var (
 		routesSub, closeRoutesSub = state.SubscribeForRoutingUpdates() // subscribe for routing updates
 		stopAutoUpdate            = state.StartAutoUpdate(ctx)         // start auto-update
+		stopIdleWatcher           = state.StartIdleWatcher(ctx)        // stop the idle autostart containers
 
 		logRoutes = func(msg string, routes map[string]map[string]url.URL) {
 			var currentRoutes = make(map[string][]string, len(routes))
@@ -325,7 +533,73 @@ func (cmd *command) makeDockerStateWatcher(
 		logRoutes("Initial Docker routing", routes)
 	}
 
-	return state, sync.OnceFunc(func() { stopAutoUpdate(); closeRoutesSub() }), nil
+	return state, sync.OnceFunc(func() { stopAutoUpdate(); stopIdleWatcher(); closeRoutesSub() }), nil
+}
+
+// makeRouter creates the routes aggregator, and runs the route providers in the background. The runtime (API)
+// routes have the highest priority, then the static routes file, the remote routes endpoint, and the docker labels
+// are the last.
+func (cmd *command) makeRouter(
+	ctx context.Context,
+	log *zap.Logger,
+	dockerState *docker.State,
+	runtimeRoutes *routestore.Store,
+	onFail func(),
+) (*routing.Aggregator, error) {
+	const dockerPriority, httpPriority, filePriority, apiPriority = 0, 10, 20, 30
+
+	var router = routing.NewAggregator(log).
+		Add(routing.NewDockerProvider(dockerState), dockerPriority).
+		Add(runtimeRoutes, apiPriority)
+
+	if u := cmd.options.routes.url; u != "" {
+		router.Add(routesfile.NewHTTPProvider(log.Named("http"), u, cmd.options.routes.pollInterval), httpPriority)
+	}
+
+	if path := cmd.options.routes.file; path != "" {
+		if _, err := routesfile.Load(path); err != nil { // fail fast on the invalid routes file
+			return nil, fmt.Errorf("failed to load the routes file: %w", err)
+		}
+
+		router.Add(routesfile.NewFileProvider(log.Named("file"), path), filePriority)
+	}
+
+	go func() {
+		if err := router.Run(ctx); err != nil {
+			onFail() // the routing is critical for us
+
+			log.Error("Routes provider failed", zap.Error(err))
+		}
+	}()
+
+	select { // wait for the initial routes from all providers
+	case <-router.Ready():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return router, nil
+}
+
+// hostPortsAddress returns the host address for the port shortcut. If it's not set, the docker host gateway is used
+// (when the app is running inside the container), or the loopback address otherwise.
+func (cmd *command) hostPortsAddress(log *zap.Logger) string {
+	if addr := cmd.options.hostPorts.address; addr != "" {
+		return addr
+	}
+
+	if !cmd.isInsideDocker() {
+		return "127.0.0.1"
+	}
+
+	gateway, err := dockerHostGateway()
+	if err != nil {
+		log.Warn("Failed to detect the docker host gateway", zap.Error(err))
+
+		return "host.docker.internal"
+	}
+
+	return gateway
 }
 
 func (*command) isInsideDocker() bool {
@@ -0,0 +1,57 @@
+package start
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// dockerHostGateway returns the default gateway IP address. When the app is running inside the container (using the
+// bridge network), the gateway is the docker host.
+func dockerHostGateway() (string, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	return parseDefaultGateway(f)
+}
+
+// parseDefaultGateway parses the routing table (in the /proc/net/route format) and returns the default gateway.
+func parseDefaultGateway(r io.Reader) (string, error) {
+	var scanner = bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		var fields = strings.Fields(scanner.Text())
+
+		// Iface, Destination, Gateway, ... (the header line is skipped, since the destination is not "00000000")
+		if len(fields) < 3 || fields[1] != "00000000" { //nolint:mnd
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != net.IPv4len {
+			return "", fmt.Errorf("wrong gateway address: %s", fields[2])
+		}
+
+		var ip = make(net.IP, net.IPv4len)
+
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw)) // the address is in the host byte order
+
+		return ip.String(), nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", errors.New("default gateway not found")
+}
@@ -0,0 +1,25 @@
+package start
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDefaultGateway(t *testing.T) {
+	t.Parallel()
+
+	const table = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	000011AC	00000000	0001	0	0	0	0000FFFF	0	0	0
+eth0	00000000	010011AC	0003	0	0	0	00000000	0	0	0
+`
+
+	ip, err := parseDefaultGateway(strings.NewReader(table))
+	require.NoError(t, err)
+	assert.Equal(t, "172.17.0.1", ip)
+
+	_, err = parseDefaultGateway(strings.NewReader("Iface	Destination	Gateway\n"))
+	assert.ErrorContains(t, err, "not found")
+}
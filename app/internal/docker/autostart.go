@@ -0,0 +1,279 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"go.uber.org/zap"
+)
+
+type (
+	// ContainerWaker starts the stopped (scale-to-zero) containers on demand.
+	ContainerWaker interface {
+		// Wake starts the stopped container with the autostart label, and waits until it's ready to serve the
+		// requests (or the context is canceled). The container is started only once, even if the method is called
+		// concurrently.
+		Wake(ctx context.Context, containerID string) error
+
+		// Touch marks the container as active, so it's not stopped by the idle watcher.
+		Touch(containerID string)
+	}
+
+	// wakeup is the container starting process.
+	wakeup struct {
+		done chan struct{} // closed when the process is finished
+		err  error         // the process error (read only after the done channel is closed)
+	}
+)
+
+const (
+	wakeTimeout       = 2 * time.Minute        // maximum amount of time to wait for the container readiness
+	wakePollInterval  = 500 * time.Millisecond // how often to check the container readiness
+	idleCheckInterval = 10 * time.Second       // how often to check the idle containers
+)
+
+// ErrWakeTimeout is returned when the container is not ready in time.
+var ErrWakeTimeout = errors.New("the container is not ready in time")
+
+// autostartOptions reads the autostart and idle timeout labels of the container.
+func (s *State) autostartOptions(labels map[string]string, diag *diagnostics) (autostart bool, idle time.Duration) {
+	for _, wantLabel := range s.labels.autostart {
+		if v, ok := labels[wantLabel]; ok {
+			if parsed, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				autostart = parsed
+			} else {
+				diag.warn("autostart", "the %s label value %q is not a boolean, ignored", wantLabel, v)
+			}
+
+			break
+		}
+	}
+
+	if !autostart {
+		return false, 0
+	}
+
+	for _, wantLabel := range s.labels.idleTimeout {
+		if v, ok := labels[wantLabel]; ok {
+			if parsed, err := time.ParseDuration(strings.TrimSpace(v)); err == nil && parsed > 0 {
+				idle = parsed
+			} else {
+				diag.warn("autostart", "the %s label value %q is not a valid duration, ignored", wantLabel, v)
+			}
+
+			break
+		}
+	}
+
+	if idle > 0 {
+		diag.ok("autostart", "the container is started on the first request, and stopped after %s of inactivity",
+			idle)
+	} else {
+		diag.ok("autostart", "the container is started on the first request")
+	}
+
+	return autostart, idle
+}
+
+// isWaking returns true if the container is being started right now.
+func (s *State) isWaking(containerID string) bool {
+	s.wakeMu.Lock()
+	defer s.wakeMu.Unlock()
+
+	_, ok := s.waking[containerID]
+
+	return ok
+}
+
+// Wake implements the [ContainerWaker] interface.
+func (s *State) Wake(ctx context.Context, containerID string) error {
+	s.wakeMu.Lock()
+
+	w, inProgress := s.waking[containerID]
+	if !inProgress {
+		w = &wakeup{done: make(chan struct{})}
+		s.waking[containerID] = w
+
+		// the process is detached from the request context, since other requests may wait for it too
+		go func() {
+			w.err = s.wake(context.WithoutCancel(ctx), containerID)
+
+			s.wakeMu.Lock()
+			delete(s.waking, containerID)
+			s.wakeMu.Unlock()
+
+			// publish the "running" (or "sleeping", on failure) route state before the waiters are released
+			_ = s.Update(context.WithoutCancel(ctx))
+
+			close(w.done)
+		}()
+	}
+
+	s.wakeMu.Unlock()
+
+	select {
+	case <-w.done:
+		return w.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wake starts the container and waits for its readiness.
+func (s *State) wake(ctx context.Context, containerID string) error {
+	ctx, cancel := context.WithTimeout(ctx, wakeTimeout)
+	defer cancel()
+
+	s.log.Info("Starting the container on request (autostart)", zap.String("id", containerID))
+
+	_ = s.Update(ctx) // publish the "starting" route state
+
+	if err := s.dc.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start the container: %w", err)
+	}
+
+	var ticker = time.NewTicker(wakePollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Update(ctx); err == nil && s.isReady(ctx, containerID) {
+			s.Touch(containerID)
+
+			s.log.Info("The container is started (autostart)", zap.String("id", containerID))
+
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrWakeTimeout
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// isReady returns true if the container is running (and healthy, if it has the healthcheck), and its upstream
+// accepts the TCP connections.
+func (s *State) isReady(ctx context.Context, containerID string) bool {
+	s.routesMu.Lock()
+	var opts, routed = s.options[containerID]
+	s.routesMu.Unlock()
+
+	if !routed || opts.Sleeping {
+		return false
+	}
+
+	inspected, err := s.dc.ContainerInspect(ctx, containerID)
+	if err != nil || inspected.State == nil || !inspected.State.Running {
+		return false
+	}
+
+	if h := inspected.State.Health; h != nil && h.Status != container.Healthy {
+		return false
+	}
+
+	for _, u := range s.AllContainerURLs() { // any hostname will do, since the upstream is the same
+		if target, ok := u[containerID]; ok {
+			var dialer = net.Dialer{Timeout: wakePollInterval}
+
+			conn, dialErr := dialer.DialContext(ctx, "tcp", target.Host)
+			if dialErr != nil {
+				return false
+			}
+
+			_ = conn.Close()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Touch implements the [ContainerWaker] interface.
+func (s *State) Touch(containerID string) {
+	s.activityMu.Lock()
+	s.activity[containerID] = time.Now()
+	s.activityMu.Unlock()
+}
+
+// StartIdleWatcher starts stopping the containers with the autostart label, which are idle (no requests) for longer
+// than their idle timeout. It returns a function to stop the watching process.
+func (s *State) StartIdleWatcher(ctx context.Context) (stop func()) {
+	var watchCtx, cancel = context.WithCancel(ctx)
+
+	go func() {
+		var ticker = time.NewTicker(idleCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-ticker.C:
+				s.stopIdle(watchCtx)
+			}
+		}
+	}()
+
+	return sync.OnceFunc(cancel)
+}
+
+// stopIdle stops the idle containers.
+func (s *State) stopIdle(ctx context.Context) {
+	var idle = make(map[string]time.Duration)
+
+	s.routesMu.Lock()
+	for id, opts := range s.options {
+		if opts.Autostart && !opts.Sleeping && opts.IdleTimeout > 0 {
+			idle[id] = opts.IdleTimeout
+		}
+	}
+	s.routesMu.Unlock()
+
+	var now, stopped = time.Now(), false
+
+	s.activityMu.Lock()
+	for id := range s.activity { // forget the containers, which are not routed anymore (or sleeping)
+		if _, ok := idle[id]; !ok {
+			delete(s.activity, id)
+		}
+	}
+
+	for id, timeout := range idle {
+		if last, ok := s.activity[id]; !ok {
+			s.activity[id] = now // the container is started by someone else (not on request), start counting
+			delete(idle, id)
+		} else if now.Sub(last) < timeout || s.isWaking(id) {
+			delete(idle, id)
+		}
+	}
+	s.activityMu.Unlock()
+
+	for id, timeout := range idle {
+		if err := s.dc.ContainerStop(ctx, id, container.StopOptions{}); err != nil {
+			s.log.Warn("Failed to stop the idle container", zap.String("id", id), zap.Error(err))
+
+			continue
+		}
+
+		s.log.Info("The idle container is stopped", zap.String("id", id), zap.Duration("idle timeout", timeout))
+
+		stopped = true
+	}
+
+	if stopped {
+		_ = s.Update(ctx) // publish the "sleeping" route state
+	}
+}
@@ -0,0 +1,253 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	dc "github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState_buildRouteToContainerAutostart(t *testing.T) {
+	t.Parallel()
+
+	var stoppedNetworks = &container.NetworkSettingsSummary{
+		Networks: map[string]*network.EndpointSettings{"bridge": {}}, // no IP address
+	}
+
+	for name, tt := range map[string]struct {
+		giveInfo  container.Summary
+		wantRoute containerRoute
+		wantFound bool
+	}{
+		"sleeping": {
+			giveInfo: container.Summary{
+				Names:           []string{"/app-1"},
+				State:           container.StateExited,
+				Labels:          map[string]string{"indocker.host": "app", "indocker.autostart": "true"},
+				NetworkSettings: stoppedNetworks,
+			},
+			wantRoute: containerRoute{
+				scheme: "http", hosts: []string{"app"}, ipAddr: "app-1", port: 80, autostart: true, sleeping: true,
+			},
+			wantFound: true,
+		},
+		"running with the idle timeout": {
+			giveInfo: container.Summary{
+				State: container.StateRunning,
+				Labels: map[string]string{
+					"indocker.host":         "app",
+					"indocker.autostart":    "1",
+					"indocker.idle-timeout": "15m",
+				},
+				NetworkSettings: withNetworks("bridge"),
+			},
+			wantRoute: containerRoute{
+				scheme:      "http",
+				hosts:       []string{"app"},
+				ipAddr:      "10.0.0.1",
+				port:        80,
+				autostart:   true,
+				idleTimeout: 15 * time.Minute,
+			},
+			wantFound: true,
+		},
+		"invalid idle timeout": {
+			giveInfo: container.Summary{
+				State: container.StateRunning,
+				Labels: map[string]string{
+					"indocker.host":         "app",
+					"indocker.autostart":    "true",
+					"indocker.idle-timeout": "forever",
+				},
+				NetworkSettings: withNetworks("bridge"),
+			},
+			wantRoute: containerRoute{scheme: "http", hosts: []string{"app"}, ipAddr: "10.0.0.1", port: 80, autostart: true},
+			wantFound: true,
+		},
+		"stopped without the autostart label": {
+			giveInfo: container.Summary{
+				Names:           []string{"/app-1"},
+				State:           container.StateExited,
+				Labels:          map[string]string{"indocker.host": "app", "indocker.idle-timeout": "1m"},
+				NetworkSettings: stoppedNetworks,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			route, found := NewState(nil).buildRouteToContainer(tt.giveInfo, nil, nil)
+
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantRoute, route)
+		})
+	}
+}
+
+// fakeEngine is the docker engine API with a single autostart container, which listens on the upstream port.
+type fakeEngine struct {
+	upstreamPort int
+
+	mu      sync.Mutex
+	running bool
+	calls   []string // the start/stop calls
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var state, ipAddr = container.StateExited, ""
+
+	if e.running {
+		state, ipAddr = container.StateRunning, "127.0.0.1"
+	}
+
+	var reply = func(v any) { w.Header().Set("Content-Type", "application/json"); _ = json.NewEncoder(w).Encode(v) }
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /v1.47/containers/json":
+		reply([]container.Summary{{
+			ID:    "app-id",
+			Names: []string{"/app-1"},
+			State: state,
+			Labels: map[string]string{
+				"indocker.host":         "app",
+				"indocker.port":         strconv.Itoa(e.upstreamPort),
+				"indocker.autostart":    "true",
+				"indocker.idle-timeout": "1m",
+			},
+			NetworkSettings: &container.NetworkSettingsSummary{
+				Networks: map[string]*network.EndpointSettings{"bridge": {IPAddress: ipAddr}},
+			},
+		}})
+	case "GET /v1.47/networks":
+		reply([]network.Summary{})
+	case "GET /v1.47/containers/app-id/json":
+		reply(container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "app-id",
+			State: &container.State{Status: state, Running: e.running},
+		}})
+	case "POST /v1.47/containers/app-id/start":
+		e.running, e.calls = true, append(e.calls, "start")
+		w.WriteHeader(http.StatusNoContent)
+	case "POST /v1.47/containers/app-id/stop":
+		e.running, e.calls = false, append(e.calls, "stop")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (e *fakeEngine) startStopCalls() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.calls...)
+}
+
+// newAutostartState creates the state, connected to the fake docker engine with the stopped autostart container.
+func newAutostartState(t *testing.T) (*State, *fakeEngine) {
+	t.Helper()
+
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = upstream.Close() })
+
+	var engine = &fakeEngine{upstreamPort: upstream.Addr().(*net.TCPAddr).Port}
+
+	var srv = httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+
+	client, err := dc.NewClientWithOpts(dc.WithHost("tcp://"+srv.Listener.Addr().String()), dc.WithVersion("1.47"))
+	require.NoError(t, err)
+
+	var state = NewState(client)
+
+	require.NoError(t, state.Update(context.Background()))
+
+	return state, engine
+}
+
+func TestState_Wake(t *testing.T) {
+	t.Parallel()
+
+	var state, engine = newAutostartState(t)
+
+	opts, ok := state.RouteOptions("app-id")
+	require.True(t, ok)
+	assert.True(t, opts.Sleeping)
+
+	var wg sync.WaitGroup
+
+	for range 3 { // concurrent requests start the container only once
+		wg.Go(func() { assert.NoError(t, state.Wake(context.Background(), "app-id")) })
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, []string{"start"}, engine.startStopCalls())
+
+	// the running route is published before the waiters are released
+	opts, ok = state.RouteOptions("app-id")
+	require.True(t, ok)
+	assert.False(t, opts.Sleeping)
+	assert.False(t, opts.Starting)
+
+	urls, ok := state.URLToContainerByHostname("app")
+	require.True(t, ok)
+	assert.Equal(t, "127.0.0.1:"+strconv.Itoa(engine.upstreamPort), urls["app-id"].Host)
+}
+
+func TestState_stopIdle(t *testing.T) {
+	t.Parallel()
+
+	var state, engine = newAutostartState(t)
+
+	require.NoError(t, state.Wake(context.Background(), "app-id"))
+
+	state.stopIdle(context.Background()) // just touched
+	assert.Equal(t, []string{"start"}, engine.startStopCalls())
+
+	state.activityMu.Lock()
+	state.activity["app-id"] = time.Now().Add(-time.Minute) // the idle timeout is reached
+	state.activityMu.Unlock()
+
+	state.stopIdle(context.Background())
+	assert.Equal(t, []string{"start", "stop"}, engine.startStopCalls())
+
+	opts, ok := state.RouteOptions("app-id")
+	require.True(t, ok)
+	assert.True(t, opts.Sleeping)
+}
+
+func TestState_stopIdleStartedBySomeoneElse(t *testing.T) {
+	t.Parallel()
+
+	var state, engine = newAutostartState(t)
+
+	engine.mu.Lock()
+	engine.running = true // e.g. using the docker CLI
+	engine.mu.Unlock()
+
+	require.NoError(t, state.Update(context.Background()))
+
+	state.stopIdle(context.Background()) // the idle period starts now
+	assert.Empty(t, engine.startStopCalls())
+
+	state.activityMu.Lock()
+	assert.WithinDuration(t, time.Now(), state.activity["app-id"], time.Minute)
+	state.activityMu.Unlock()
+}
@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+type (
+	// ConflictPolicy defines how the hostname, claimed by the containers from different projects (or images), is
+	// routed.
+	ConflictPolicy string
+
+	// RouteConflict describes the hostname, claimed by the containers from different projects (or images).
+	RouteConflict struct {
+		Hostname   string
+		Policy     ConflictPolicy
+		Containers []string // IDs of all containers claiming the hostname (sorted)
+		Winners    []string // IDs of the containers the hostname is routed to (sorted, empty if rejected)
+	}
+
+	RouteConflictsResolver interface {
+		// RouteConflicts returns the detected route conflicts, sorted by the hostname.
+		RouteConflicts() []RouteConflict
+	}
+)
+
+const (
+	ConflictPolicyMerge    ConflictPolicy = "merge"    // balance the traffic between all containers (legacy behavior)
+	ConflictPolicyNewest   ConflictPolicy = "newest"   // route to the most recently created container(s)
+	ConflictPolicyPriority ConflictPolicy = "priority" // route to the container(s) with the highest priority label
+	ConflictPolicyReject   ConflictPolicy = "reject"   // do not route the hostname at all
+)
+
+// ConflictPolicies returns all supported conflict policies.
+func ConflictPolicies() []ConflictPolicy {
+	return []ConflictPolicy{ConflictPolicyMerge, ConflictPolicyNewest, ConflictPolicyPriority, ConflictPolicyReject}
+}
+
+// ParseConflictPolicy parses the conflict policy from the string (case-insensitive).
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	var p = ConflictPolicy(strings.ToLower(strings.TrimSpace(s)))
+
+	if slices.Contains(ConflictPolicies(), p) {
+		return p, nil
+	}
+
+	return "", fmt.Errorf("unknown route conflict policy: %s", s)
+}
+
+// Rejected returns true if the hostname is not routed because of the conflict.
+func (c RouteConflict) Rejected() bool { return len(c.Winners) == 0 }
+
+// String returns a human-readable description of the conflict.
+func (c RouteConflict) String() string {
+	var all, winners = make([]string, len(c.Containers)), make([]string, len(c.Winners))
+
+	for i, id := range c.Containers {
+		all[i] = shortID(id)
+	}
+
+	for i, id := range c.Winners {
+		winners[i] = shortID(id)
+	}
+
+	var msg = fmt.Sprintf("the hostname %q is claimed by containers from different projects or images (%s)",
+		c.Hostname, strings.Join(all, ", "),
+	)
+
+	switch {
+	case c.Rejected():
+		return msg + fmt.Sprintf(", so it is not routed (policy: %s)", c.Policy)
+	case c.Policy == ConflictPolicyMerge:
+		return msg + fmt.Sprintf(", the traffic is balanced between them (policy: %s)", c.Policy)
+	default:
+		return msg + fmt.Sprintf(", routed to %s (policy: %s)", strings.Join(winners, ", "), c.Policy)
+	}
+}
+
+//nolint:gochecknoglobals
+var projectLabels = []string{composeProjectLabel, "com.docker.stack.namespace"}
+
+// routeCandidate is a container, claiming the hostname.
+type routeCandidate struct {
+	id       string
+	url      url.URL
+	group    string // the compose project (or the image, if the container is not a part of a project)
+	created  int64  // creation time (unix timestamp)
+	priority int    // the priority label value (0 by default)
+}
+
+// newRouteCandidate creates the route candidate using the container info and the priority label names.
+func newRouteCandidate(info container.Summary, u url.URL, priorityLabels []string) routeCandidate {
+	var c = routeCandidate{id: info.ID, url: u, group: "image:" + info.Image, created: info.Created}
+
+	for _, label := range projectLabels {
+		if v := strings.TrimSpace(info.Labels[label]); v != "" {
+			c.group = "project:" + v
+
+			break
+		}
+	}
+
+	for _, label := range priorityLabels {
+		if v, err := strconv.Atoi(strings.TrimSpace(info.Labels[label])); err == nil {
+			c.priority = v
+
+			break
+		}
+	}
+
+	return c
+}
+
+// resolveConflict picks the containers the hostname should be routed to. Containers from the same group (e.g. the
+// replicas of the same compose service) are never considered as conflicting. If the candidates belong to different
+// groups, the conflict is returned along with the winners, chosen by the policy.
+func resolveConflict(
+	policy ConflictPolicy,
+	hostname string,
+	candidates []routeCandidate,
+) (winners []routeCandidate, conflict *RouteConflict) {
+	// group the candidates
+	var groups = make(map[string][]routeCandidate)
+
+	for _, c := range candidates {
+		groups[c.group] = append(groups[c.group], c)
+	}
+
+	if len(groups) < 2 { //nolint:mnd
+		return candidates, nil // no conflict
+	}
+
+	conflict = &RouteConflict{Hostname: hostname, Policy: policy, Containers: make([]string, 0, len(candidates))}
+
+	for _, c := range candidates {
+		conflict.Containers = append(conflict.Containers, c.id)
+	}
+
+	switch policy {
+	case ConflictPolicyReject:
+		winners = nil
+
+	case ConflictPolicyNewest, ConflictPolicyPriority:
+		var (
+			bestGroup string
+			best      routeCandidate
+		)
+
+		// compare the groups using their "best" containers (the highest priority, then the newest one)
+		for group, members := range groups {
+			var top = members[0]
+
+			for _, m := range members[1:] {
+				if compareCandidates(policy, m, top) > 0 {
+					top = m
+				}
+			}
+
+			// the group name is used as the last resort to make the result deterministic
+			if bestGroup == "" || compareCandidates(policy, top, best) > 0 ||
+				(compareCandidates(policy, top, best) == 0 && group < bestGroup) {
+				bestGroup, best = group, top
+			}
+		}
+
+		winners = groups[bestGroup]
+
+	default: // ConflictPolicyMerge
+		winners = candidates
+	}
+
+	conflict.Winners = make([]string, 0, len(winners))
+
+	for _, w := range winners {
+		conflict.Winners = append(conflict.Winners, w.id)
+	}
+
+	slices.Sort(conflict.Containers)
+	slices.Sort(conflict.Winners)
+
+	return winners, conflict
+}
+
+// compareCandidates compares two candidates according to the policy. The result is positive if a is preferred.
+func compareCandidates(policy ConflictPolicy, a, b routeCandidate) int {
+	if policy == ConflictPolicyPriority {
+		if c := cmp.Compare(a.priority, b.priority); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(a.created, b.created)
+}
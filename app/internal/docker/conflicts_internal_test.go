@@ -0,0 +1,119 @@
+package docker
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConflictPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range ConflictPolicies() {
+		parsed, err := ParseConflictPolicy(" " + string(p) + " ")
+		require.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+
+	_, err := ParseConflictPolicy("foo")
+	assert.Error(t, err)
+}
+
+func TestResolveConflict(t *testing.T) {
+	t.Parallel()
+
+	var candidate = func(id, project string, created int64, priority string) routeCandidate {
+		return newRouteCandidate(container.Summary{
+			ID:      id,
+			Image:   "nginx",
+			Created: created,
+			Labels:  map[string]string{"com.docker.compose.project": project, "indocker.priority": priority},
+		}, url.URL{Scheme: "http", Host: id + ":80"}, []string{"indocker.priority"})
+	}
+
+	var (
+		replicas = []routeCandidate{candidate("a1", "a", 1, ""), candidate("a2", "a", 2, "")}
+		mixed    = []routeCandidate{
+			candidate("a1", "a", 1, "10"),
+			candidate("a2", "a", 2, ""),
+			candidate("b1", "b", 3, ""),
+		}
+	)
+
+	for name, tt := range map[string]struct {
+		givePolicy     ConflictPolicy
+		giveCandidates []routeCandidate
+		wantWinners    []string
+		wantConflict   bool
+	}{
+		"replicas are not conflicting": {
+			givePolicy:     ConflictPolicyReject,
+			giveCandidates: replicas,
+			wantWinners:    []string{"a1", "a2"},
+		},
+		"merge": {
+			givePolicy:     ConflictPolicyMerge,
+			giveCandidates: mixed,
+			wantWinners:    []string{"a1", "a2", "b1"},
+			wantConflict:   true,
+		},
+		"newest": {
+			givePolicy:     ConflictPolicyNewest,
+			giveCandidates: mixed,
+			wantWinners:    []string{"b1"},
+			wantConflict:   true,
+		},
+		"priority": {
+			givePolicy:     ConflictPolicyPriority,
+			giveCandidates: mixed,
+			wantWinners:    []string{"a1", "a2"},
+			wantConflict:   true,
+		},
+		"priority (equal, newest wins)": {
+			givePolicy: ConflictPolicyPriority,
+			giveCandidates: []routeCandidate{
+				candidate("a1", "a", 5, "1"),
+				candidate("b1", "b", 3, "1"),
+			},
+			wantWinners:  []string{"a1"},
+			wantConflict: true,
+		},
+		"reject": {
+			givePolicy:     ConflictPolicyReject,
+			giveCandidates: mixed,
+			wantWinners:    []string{},
+			wantConflict:   true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			winners, conflict := resolveConflict(tt.givePolicy, "foo", tt.giveCandidates)
+
+			var ids = make([]string, 0, len(winners))
+
+			for _, w := range winners {
+				ids = append(ids, w.id)
+			}
+
+			assert.ElementsMatch(t, tt.wantWinners, ids)
+
+			if !tt.wantConflict {
+				assert.Nil(t, conflict)
+
+				return
+			}
+
+			require.NotNil(t, conflict)
+			assert.Equal(t, "foo", conflict.Hostname)
+			assert.Equal(t, tt.givePolicy, conflict.Policy)
+			assert.Len(t, conflict.Containers, len(tt.giveCandidates))
+			assert.ElementsMatch(t, tt.wantWinners, conflict.Winners)
+			assert.Equal(t, len(tt.wantWinners) == 0, conflict.Rejected())
+			assert.Contains(t, conflict.String(), `"foo"`)
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+type (
+	// DiagnosticLevel is a level of the diagnostic step result.
+	DiagnosticLevel string
+
+	// DiagnosticStep is a single step of the routing decision path.
+	DiagnosticStep struct {
+		Name    string          // short name of the step (e.g. "host label")
+		Level   DiagnosticLevel // the step result level
+		Message string          // human-readable description of the step result
+	}
+
+	// Diagnosis describes why the container is (or is not) routed.
+	Diagnosis struct {
+		ContainerID   string
+		ContainerName string
+		Routed        bool     // true if the container is routed
+		Hostname      string   // the resolved hostname (without the ".indocker.app" suffix), if any
+		URL           *url.URL // the resolved upstream URL, if any
+		Steps         []DiagnosticStep
+	}
+
+	// ContainerDiagnoser explains why the container is (or is not) routed.
+	ContainerDiagnoser interface {
+		Diagnose(ctx context.Context, containerIDOrName string) (*Diagnosis, error)
+	}
+)
+
+const (
+	DiagnosticOK    DiagnosticLevel = "ok"
+	DiagnosticInfo  DiagnosticLevel = "info"
+	DiagnosticWarn  DiagnosticLevel = "warn"
+	DiagnosticError DiagnosticLevel = "error"
+)
+
+// reservedHostnames are the hostnames used by indocker itself, so they can't be routed to the containers.
+var reservedHostnames = []string{"monitor"} //nolint:gochecknoglobals
+
+// IsReservedHostname returns true if the hostname is used by indocker itself.
+func IsReservedHostname(hostname string) bool { return slices.Contains(reservedHostnames, hostname) }
+
+// Diagnose walks the same decision path as the routing does for the container with the given ID or name, and
+// reports every step. Additionally, it checks the hostname for conflicts with other containers (and the reserved
+// hostnames), and tries to connect to the resolved upstream.
+func (s *State) Diagnose(ctx context.Context, containerIDOrName string) (*Diagnosis, error) { //nolint:funlen
+	inspected, inspectErr := s.dc.ContainerInspect(ctx, containerIDOrName)
+	if inspectErr != nil {
+		if errdefs.IsNotFound(inspectErr) {
+			return nil, fmt.Errorf("%w: %s", ErrContainerNotFound, containerIDOrName)
+		}
+
+		return nil, fmt.Errorf("failed to inspect the container: %w", inspectErr)
+	}
+
+	// the routing is built using the containers list (not the inspection) result, so we need to get it
+	list, listErr := s.dc.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("id", inspected.ID)),
+	})
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to list the containers: %w", listErr)
+	} else if len(list) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrContainerNotFound, containerIDOrName)
+	}
+
+	var (
+		info   = list[0]
+		diag   = new(diagnostics)
+		result = Diagnosis{ContainerID: info.ID, ContainerName: strings.TrimPrefix(inspected.Name, "/")}
+	)
+
+	var env map[string]string
+
+	if s.virtualHosts && inspected.Config != nil {
+		env = virtualHostVars(inspected.Config.Env)
+	}
+
+	route, found := s.buildRouteToContainer(info, env, diag)
+
+	// only alive containers are routed (the same filter is used for the state updating), except the stopped ones
+	// with the autostart label
+	if !slices.Contains(aliveContainerStatuses, info.State) && !route.sleeping {
+		diag.fail("container state", "containers in the %q state are not routed", info.State)
+
+		found = false
+	}
+
+	if found {
+		var routed = route.hosts[:0:0]
+
+		for _, hostname := range route.hosts {
+			if s.diagnoseHostname(diag, info.ID, hostname) {
+				routed = append(routed, hostname)
+			}
+		}
+
+		route.hosts, found = routed, len(routed) > 0
+	}
+
+	if found {
+		var u = route.url()
+
+		result.Routed, result.Hostname, result.URL = true, route.hosts[0], &u
+
+		for _, hostname := range route.hosts {
+			diag.ok("route", "https://%s.indocker.app%s -> %s", hostname, route.pathPrefix, u.String())
+		}
+
+		if route.sleeping {
+			diag.info("tcp dial", "skipped, the container is stopped")
+		} else {
+			s.probeUpstream(ctx, diag, *result.URL)
+		}
+	} else {
+		diag.fail("route", "the container is not routed")
+	}
+
+	s.diagnosePortForwards(diag, info)
+
+	result.Steps = diag.steps
+
+	return &result, nil
+}
+
+// diagnoseHostname checks the hostname for conflicts with other containers and the reserved hostnames. It returns
+// false if the hostname is reserved, or the container loses the route conflict (so the traffic is not routed to it).
+func (s *State) diagnoseHostname(diag *diagnostics, containerID, hostname string) bool {
+	if IsReservedHostname(hostname) {
+		diag.fail("hostname", "the hostname %q is reserved by indocker and shadowed by the monitor dashboard",
+			hostname,
+		)
+
+		return false
+	}
+
+	for _, conflict := range s.RouteConflicts() {
+		if conflict.Hostname != hostname {
+			continue
+		}
+
+		if slices.Contains(conflict.Winners, containerID) {
+			diag.warn("hostname", "%s", conflict.String())
+
+			return true
+		}
+
+		diag.fail("hostname", "%s", conflict.String())
+
+		return false
+	}
+
+	var others = make([]string, 0)
+
+	for id := range s.AllContainerURLs()[hostname] {
+		if id != containerID {
+			others = append(others, shortID(id))
+		}
+	}
+
+	if len(others) > 0 {
+		slices.Sort(others)
+
+		diag.ok("hostname", "the hostname %q is shared with the replicas (%s), the traffic is balanced between them",
+			hostname, strings.Join(others, ", "),
+		)
+
+		return true
+	}
+
+	diag.ok("hostname", "the hostname %q is not claimed by other containers", hostname)
+
+	return true
+}
+
+// diagnosePortForwards checks the port forwarding labels of the container, and the listen ports for conflicts with
+// other containers.
+func (s *State) diagnosePortForwards(diag *diagnostics, info container.Summary) {
+	var forwards = s.portForwards(info.Labels, diag)
+
+	if len(forwards) > 0 && info.State != container.StateRunning {
+		diag.warn("port forwarding", "only running containers are forwarded (state: %s)", info.State)
+
+		return
+	}
+
+	for _, fw := range forwards {
+		for _, active := range s.PortForwards() {
+			if active.Protocol == fw.Protocol && active.ListenPort == fw.ListenPort && active.ContainerID != info.ID {
+				diag.fail("port forwarding", "the %s port %d is already forwarded to the container %s",
+					strings.ToUpper(fw.Protocol), fw.ListenPort, shortID(active.ContainerID),
+				)
+			}
+		}
+	}
+}
+
+// probeUpstream tries to connect to the upstream (TCP) and to send an HTTP request to it.
+func (*State) probeUpstream(ctx context.Context, diag *diagnostics, u url.URL) {
+	const timeout = 3 * time.Second
+
+	var dialer = net.Dialer{Timeout: timeout}
+
+	conn, dialErr := dialer.DialContext(ctx, "tcp", u.Host)
+	if dialErr != nil {
+		diag.fail("tcp dial", "failed to connect to %s: %s", u.Host, dialErr)
+
+		return
+	}
+
+	_ = conn.Close()
+
+	diag.ok("tcp dial", "connected to %s", u.Host)
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		diag.info("http request", "skipped for the %q scheme", u.Scheme)
+
+		return
+	}
+
+	var client = http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, //nolint:gosec
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse // do not follow redirects
+		},
+	}
+
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, u.String()+"/", http.NoBody)
+	if reqErr != nil {
+		diag.fail("http request", "failed to create the request: %s", reqErr)
+
+		return
+	}
+
+	resp, respErr := client.Do(req)
+	if respErr != nil {
+		diag.fail("http request", "request to %s failed: %s", req.URL.String(), respErr)
+
+		return
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		diag.warn("http request", "%s responded with %s", req.URL.String(), resp.Status)
+	} else {
+		diag.ok("http request", "%s responded with %s", req.URL.String(), resp.Status)
+	}
+}
+
+// shortID returns the short (12 characters) container ID.
+func shortID(id string) string {
+	const shortLen = 12
+
+	if len(id) > shortLen {
+		return id[:shortLen]
+	}
+
+	return id
+}
+
+// diagnostics collects the diagnostic steps. All methods are nil-safe, so the nil value can be used to disable
+// the collecting.
+type diagnostics struct{ steps []DiagnosticStep }
+
+func (d *diagnostics) add(level DiagnosticLevel, name, format string, args ...any) {
+	if d == nil {
+		return
+	}
+
+	d.steps = append(d.steps, DiagnosticStep{Name: name, Level: level, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *diagnostics) ok(name, format string, args ...any) {
+	d.add(DiagnosticOK, name, format, args...)
+}
+func (d *diagnostics) info(name, format string, args ...any) {
+	d.add(DiagnosticInfo, name, format, args...)
+}
+func (d *diagnostics) warn(name, format string, args ...any) {
+	d.add(DiagnosticWarn, name, format, args...)
+}
+func (d *diagnostics) fail(name, format string, args ...any) {
+	d.add(DiagnosticError, name, format, args...)
+}
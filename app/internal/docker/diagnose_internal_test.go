@@ -0,0 +1,327 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func withNetworks(names ...string) *container.NetworkSettingsSummary {
+	var nets = make(map[string]*network.EndpointSettings, len(names))
+
+	for _, name := range names {
+		nets[name] = &network.EndpointSettings{IPAddress: "10.0.0.1"}
+	}
+
+	return &container.NetworkSettingsSummary{Networks: nets}
+}
+
+func TestState_buildRouteToContainerDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveInfo   container.Summary
+		wantFound  bool
+		wantLevels map[string]DiagnosticLevel
+	}{
+		"routed": {
+			giveInfo: container.Summary{
+				State:           container.StateRunning,
+				Labels:          map[string]string{"indocker.host": "foo", "indocker.port": "8080"},
+				NetworkSettings: withNetworks("bridge"),
+			},
+			wantFound: true,
+			wantLevels: map[string]DiagnosticLevel{
+				"container state": DiagnosticOK,
+				"host label":      DiagnosticOK,
+				"port label":      DiagnosticOK,
+				"network":         DiagnosticOK,
+				"ip address":      DiagnosticOK,
+			},
+		},
+		"no host label": {
+			giveInfo: container.Summary{
+				State:           container.StateRunning,
+				Labels:          map[string]string{"foo": "bar"},
+				NetworkSettings: withNetworks("bridge"),
+			},
+			wantLevels: map[string]DiagnosticLevel{"host label": DiagnosticError},
+		},
+		"typo in the network name": {
+			giveInfo: container.Summary{
+				State:           container.StateExited,
+				Labels:          map[string]string{"indocker.host": "foo", "indocker.network": "fronted"},
+				NetworkSettings: withNetworks("frontend"),
+			},
+			wantFound: true,
+			wantLevels: map[string]DiagnosticLevel{
+				"container state": DiagnosticWarn,
+				"network":         DiagnosticWarn,
+			},
+		},
+		"template error": {
+			giveInfo: container.Summary{
+				State:           container.StateRunning,
+				Labels:          map[string]string{"indocker.host": "{{.Foo}}"},
+				NetworkSettings: withNetworks("bridge"),
+			},
+			wantLevels: map[string]DiagnosticLevel{"host label": DiagnosticError},
+		},
+		"invalid port": {
+			giveInfo: container.Summary{
+				State:           container.StateRunning,
+				Labels:          map[string]string{"indocker.host": "foo", "indocker.port": "http"},
+				NetworkSettings: withNetworks("bridge"),
+			},
+			wantFound:  true,
+			wantLevels: map[string]DiagnosticLevel{"port label": DiagnosticWarn},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var diag = new(diagnostics)
+
+			_, found := NewState(nil).buildRouteToContainer(tt.giveInfo, nil, diag)
+
+			assert.Equal(t, tt.wantFound, found)
+
+			var levels = make(map[string]DiagnosticLevel, len(diag.steps))
+
+			for _, step := range diag.steps {
+				levels[step.Name] = step.Level
+			}
+
+			for stepName, wantLevel := range tt.wantLevels {
+				assert.Equal(t, wantLevel, levels[stepName], stepName)
+			}
+		})
+	}
+}
+
+func TestState_diagnoseHostname(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveHostname string
+		wantRouted   bool
+		wantLevel    DiagnosticLevel
+	}{
+		"free hostname":     {giveHostname: "foo", wantRouted: true, wantLevel: DiagnosticOK},
+		"reserved hostname": {giveHostname: "monitor", wantLevel: DiagnosticError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var diag = new(diagnostics)
+
+			assert.Equal(t, tt.wantRouted, NewState(nil).diagnoseHostname(diag, "abc", tt.giveHostname))
+
+			if assert.Len(t, diag.steps, 1) {
+				assert.Equal(t, "hostname", diag.steps[0].Name)
+				assert.Equal(t, tt.wantLevel, diag.steps[0].Level)
+			}
+		})
+	}
+}
+
+func TestState_buildRouteToContainer(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveOpts    []StateOption
+		giveLabels  map[string]string
+		giveEnv     []string
+		giveDomains map[string]string
+		wantRoute   containerRoute
+		wantFound   bool
+	}{
+		"labels": {
+			giveLabels: map[string]string{"indocker.host": "Foo.indocker.app", "indocker.port": "8080"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"foo"}, ipAddr: "10.0.0.1", port: 8080},
+			wantFound:  true,
+		},
+		"multiple hosts in the label": {
+			giveLabels: map[string]string{"indocker.host": "foo, bar.indocker.app,,foo", "indocker.path": "api/"},
+			wantRoute: containerRoute{
+				scheme: "http", hosts: []string{"foo", "bar"}, ipAddr: "10.0.0.1", port: 80, pathPrefix: "/api",
+			},
+			wantFound: true,
+		},
+		"grpc scheme": {
+			giveLabels: map[string]string{"indocker.host": "api", "indocker.scheme": "gRPCs"},
+			wantRoute:  containerRoute{scheme: "grpcs", hosts: []string{"api"}, ipAddr: "10.0.0.1", port: 443},
+			wantFound:  true,
+		},
+		"unsupported scheme": {
+			giveLabels: map[string]string{"indocker.host": "app", "indocker.scheme": "ftp"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"app"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound:  true,
+		},
+		"tls passthrough": {
+			giveLabels: map[string]string{"indocker.host": "vault", "indocker.tls.passthrough": "true"},
+			wantRoute: containerRoute{
+				scheme: "https", hosts: []string{"vault"}, ipAddr: "10.0.0.1", port: 443, passthrough: true,
+			},
+			wantFound: true,
+		},
+		"tls passthrough with the port": {
+			giveLabels: map[string]string{
+				"indocker.host": "vault", "indocker.port": "8200", "indocker.tls.passthrough": "1",
+			},
+			wantRoute: containerRoute{
+				scheme: "https", hosts: []string{"vault"}, ipAddr: "10.0.0.1", port: 8200, passthrough: true,
+			},
+			wantFound: true,
+		},
+		"invalid tls passthrough": {
+			giveLabels: map[string]string{"indocker.host": "vault", "indocker.tls.passthrough": "yes please"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"vault"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound:  true,
+		},
+		"upstream tls": {
+			giveLabels: map[string]string{
+				"indocker.host":            "api",
+				"indocker.scheme":          "https",
+				"indocker.tls.ca":          "/certs/ca.pem",
+				"indocker.tls.server-name": "api.internal",
+				"indocker.tls.cert":        "/certs/client.pem",
+				"indocker.tls.key":         "/certs/client.key",
+			},
+			wantRoute: containerRoute{
+				scheme: "https", hosts: []string{"api"}, ipAddr: "10.0.0.1", port: 443, upstreamTLS: UpstreamTLS{
+					Verify:     true,
+					CAFile:     "/certs/ca.pem",
+					ServerName: "api.internal",
+					CertFile:   "/certs/client.pem",
+					KeyFile:    "/certs/client.key",
+				},
+			},
+			wantFound: true,
+		},
+		"upstream tls without the verification": {
+			giveLabels: map[string]string{
+				"indocker.host":       "api",
+				"indocker.scheme":     "grpcs",
+				"indocker.tls.ca":     "/certs/ca.pem",
+				"indocker.tls.cert":   "/certs/client.pem", // no key
+				"indocker.tls.verify": "false",
+			},
+			wantRoute: containerRoute{
+				scheme: "grpcs", hosts: []string{"api"}, ipAddr: "10.0.0.1", port: 443,
+				upstreamTLS: UpstreamTLS{CAFile: "/certs/ca.pem"},
+			},
+			wantFound: true,
+		},
+		"upstream tls for the plain http": {
+			giveLabels: map[string]string{"indocker.host": "api", "indocker.tls.verify": "true"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"api"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound:  true,
+		},
+		"nginx-proxy env": {
+			giveEnv: []string{
+				"PATH=/usr/bin",
+				"VIRTUAL_HOST=app.indocker.app,*.example.com,www.indocker.app",
+				"VIRTUAL_PORT=3000",
+				"VIRTUAL_PROTO=https",
+				"VIRTUAL_PATH=/app/",
+			},
+			wantRoute: containerRoute{
+				scheme: "https", hosts: []string{"app", "www"}, ipAddr: "10.0.0.1", port: 3000, pathPrefix: "/app",
+			},
+			wantFound: true,
+		},
+		"unsupported nginx-proxy protocol": {
+			giveEnv:   []string{"VIRTUAL_HOST=app", "VIRTUAL_PROTO=uwsgi"},
+			wantRoute: containerRoute{scheme: "http", hosts: []string{"app"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound: true,
+		},
+		"labels take precedence over the env": {
+			giveLabels: map[string]string{"indocker.host": "foo"},
+			giveEnv:    []string{"VIRTUAL_HOST=bar", "VIRTUAL_PORT=3000"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"foo"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound:  true,
+		},
+		"only wildcards": {
+			giveEnv: []string{"VIRTUAL_HOST=*.example.com"},
+		},
+		"bare labels": {
+			giveLabels: map[string]string{"host": "foo", "port": "8080"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"foo"}, ipAddr: "10.0.0.1", port: 8080},
+			wantFound:  true,
+		},
+		"bare labels in the strict mode": {
+			giveOpts:   []StateOption{WithStrictLabels(true)},
+			giveLabels: map[string]string{"host": "foo", "port": "8080"},
+		},
+		"custom prefix": {
+			giveOpts:   []StateOption{WithLabelPrefix("dev"), WithStrictLabels(true)},
+			giveLabels: map[string]string{"indocker.host": "foo", "dev.host": "bar", "dev.port": "8080", "port": "1"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"bar"}, ipAddr: "10.0.0.1", port: 8080},
+			wantFound:  true,
+		},
+		"network domain": {
+			giveLabels:  map[string]string{"com.docker.compose.service": "web_app"},
+			giveDomains: map[string]string{"feature-x": "feature-x", "feature-y": "feature-y"},
+			wantRoute: containerRoute{
+				scheme:  "http",
+				hosts:   []string{"web-app.feature-x", "web-app.feature-y"},
+				ipAddr:  "10.0.0.1",
+				port:    3000,
+				network: "feature-x",
+			},
+			wantFound: true,
+		},
+		"host label takes precedence over the network domain": {
+			giveLabels:  map[string]string{"indocker.host": "foo"},
+			giveDomains: map[string]string{"feature-x": "feature-x"},
+			wantRoute:   containerRoute{scheme: "http", hosts: []string{"foo"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound:   true,
+		},
+		"template": {
+			giveLabels: map[string]string{
+				"indocker.host":                       "{{.Compose.Service}}-{{.Compose.Project}}, {{.Name}}-{{.Replica}}",
+				"com.docker.compose.service":          "web",
+				"com.docker.compose.project":          "Feature-X",
+				"com.docker.compose.container-number": "2",
+			},
+			wantRoute: containerRoute{
+				scheme: "http", hosts: []string{"web-feature-x", "app-1-2"}, ipAddr: "10.0.0.1", port: 80,
+			},
+			wantFound: true,
+		},
+		"template with the invalid hostname": {
+			giveLabels: map[string]string{"indocker.host": "{{.Labels.branch}}, {{.Name}}", "branch": "feat/foo"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"app-1"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound:  true,
+		},
+		"template with the missing label": {
+			giveLabels: map[string]string{"indocker.host": "{{.Labels.branch}}.app"},
+		},
+		"custom prefix ignores the default one": {
+			giveOpts:   []StateOption{WithLabelPrefix("dev.")},
+			giveLabels: map[string]string{"indocker.host": "foo"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var state = NewState(nil, tt.giveOpts...)
+
+			state.domains = tt.giveDomains
+
+			route, found := state.buildRouteToContainer(container.Summary{
+				Names:           []string{"/app-1"},
+				State:           container.StateRunning,
+				Labels:          tt.giveLabels,
+				Ports:           []container.Port{{PrivatePort: 3000, Type: "tcp"}},
+				NetworkSettings: withNetworks("bridge", "feature-x", "feature-y"),
+			}, virtualHostVars(tt.giveEnv), nil)
+
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantRoute, route)
+		})
+	}
+}
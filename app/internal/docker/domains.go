@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// listNetworkDomains returns the domains, set using the network labels (e.g. "indocker.domain=feature-x"), so every
+// container, attached to the network, is routed as "<service>.feature-x". The map key is the network name. It returns
+// nil if the network domains are disabled.
+func (s *State) listNetworkDomains(ctx context.Context) (map[string]string, error) {
+	if !s.networkDomains {
+		return nil, nil
+	}
+
+	// https://docs.docker.com/engine/api/v1.46/#tag/Network/operation/NetworkList
+	list, err := s.dc.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var domains = make(map[string]string)
+
+	for _, n := range list {
+		for _, wantDomainLabel := range s.labels.domain {
+			if v := normalizeHostname(n.Labels[wantDomainLabel]); v != "" {
+				domains[n.Name] = v
+
+				break
+			}
+		}
+	}
+
+	return domains, nil
+}
+
+// domainHosts returns the hostnames of the container, attached to the networks with the domain label (the service
+// name, or the container name, followed by the domain). The network to use is returned too (the first one, sorted by
+// name, if the container is attached to a few networks with the domain label).
+func (s *State) domainHosts(info container.Summary, diag *diagnostics) (hosts []string, netName string) {
+	if info.NetworkSettings == nil || len(info.NetworkSettings.Networks) == 0 {
+		return nil, ""
+	}
+
+	s.domainsMu.Lock()
+	var domains = s.domains
+	s.domainsMu.Unlock()
+
+	if len(domains) == 0 {
+		return nil, ""
+	}
+
+	var service = strings.TrimSpace(info.Labels[composeServiceLabel])
+
+	if service == "" && len(info.Names) > 0 {
+		service = strings.TrimPrefix(info.Names[0], "/")
+	}
+
+	// the service (and container) names may contain underscores, which are not allowed in hostnames
+	if service = strings.ReplaceAll(strings.ToLower(service), "_", "-"); service == "" {
+		return nil, ""
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(info.NetworkSettings.Networks)) {
+		domain, ok := domains[name]
+		if !ok {
+			continue
+		}
+
+		if netName == "" {
+			netName = name
+		}
+
+		if h := service + "." + domain; !slices.Contains(hosts, h) {
+			hosts = append(hosts, h)
+
+			diag.ok("host label", "the hostname %q is set using the domain label of the %q network", h, name)
+		}
+	}
+
+	return hosts, netName
+}
@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	dc "github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNetworksEngine is the docker engine API with a single compose service container, attached to the network with
+// the domain label. The domain can be changed (and the network event is sent), and the networks list can fail.
+type fakeNetworksEngine struct {
+	events chan events.Message
+
+	mu           sync.Mutex
+	domain       string
+	networksFail bool
+}
+
+func (e *fakeNetworksEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var reply = func(v any) { w.Header().Set("Content-Type", "application/json"); _ = json.NewEncoder(w).Encode(v) }
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /v1.47/containers/json":
+		reply([]container.Summary{{
+			ID:     "web-id",
+			Names:  []string{"/project-web-1"},
+			State:  container.StateRunning,
+			Labels: map[string]string{composeServiceLabel: "web"},
+			NetworkSettings: &container.NetworkSettingsSummary{
+				Networks: map[string]*network.EndpointSettings{"feature": {IPAddress: "10.0.0.2"}},
+			},
+		}})
+	case "GET /v1.47/networks":
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		if e.networksFail {
+			http.Error(w, "boom", http.StatusInternalServerError)
+
+			return
+		}
+
+		reply([]network.Summary{
+			{Name: "bridge"},
+			{Name: "feature", Labels: map[string]string{"indocker.domain": e.domain}},
+		})
+	case "GET /v1.47/events":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case msg := <-e.events:
+				_ = json.NewEncoder(w).Encode(msg)
+				w.(http.Flusher).Flush()
+			}
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// set changes the network domain and the networks list failure mode, and sends the network event.
+func (e *fakeNetworksEngine) set(domain string, networksFail bool) {
+	e.mu.Lock()
+	e.domain, e.networksFail = domain, networksFail
+	e.mu.Unlock()
+
+	e.events <- events.Message{Type: events.NetworkEventType, Action: events.ActionUpdate}
+}
+
+func TestState_networkDomainsUpdate(t *testing.T) {
+	t.Parallel()
+
+	var engine = &fakeNetworksEngine{events: make(chan events.Message), domain: "feature-x"}
+
+	var srv = httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+
+	client, err := dc.NewClientWithOpts(dc.WithHost("tcp://"+srv.Listener.Addr().String()), dc.WithVersion("1.47"))
+	require.NoError(t, err)
+
+	var state = NewState(client)
+
+	require.NoError(t, state.Update(context.Background()))
+
+	var routedTo = func(hostname string) string {
+		if urls, ok := state.URLToContainerByHostname(hostname); ok {
+			var u = urls["web-id"]
+
+			return u.Host
+		}
+
+		return ""
+	}
+
+	assert.Equal(t, "10.0.0.2:80", routedTo("web.feature-x"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	t.Cleanup(state.StartAutoUpdate(ctx))
+
+	// the domain label is changed
+	engine.set("feature-y", false)
+
+	assert.Eventually(t, func() bool {
+		return routedTo("web.feature-y") != "" && routedTo("web.feature-x") == ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the networks list fails - the update succeeds, and the previous domains are kept
+	engine.set("feature-z", true)
+
+	require.NoError(t, state.Update(context.Background()))
+	assert.Equal(t, "10.0.0.2:80", routedTo("web.feature-y"))
+	assert.Empty(t, routedTo("web.feature-z"))
+}
+
+func TestState_networkDomainsDisabled(t *testing.T) {
+	t.Parallel()
+
+	var engine = &fakeNetworksEngine{events: make(chan events.Message), domain: "feature-x"}
+
+	var srv = httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+
+	client, err := dc.NewClientWithOpts(dc.WithHost("tcp://"+srv.Listener.Addr().String()), dc.WithVersion("1.47"))
+	require.NoError(t, err)
+
+	var state = NewState(client, WithNetworkDomains(false))
+
+	require.NoError(t, state.Update(context.Background()))
+
+	_, found := state.URLToContainerByHostname("web.feature-x")
+	assert.False(t, found)
+}
@@ -0,0 +1,186 @@
+package docker
+
+import (
+	"cmp"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+type (
+	// PortForward is the raw TCP (or UDP) port forwarding from the host to the container, set using the labels like
+	// "indocker.tcp.5432=listen:15432".
+	PortForward struct {
+		Protocol    string // the protocol ("tcp" or "udp")
+		ListenPort  uint16 // the port to listen on (on the host)
+		ContainerID string
+		Target      string // the current container address (ip:port)
+	}
+
+	PortForwardsResolver interface {
+		// PortForwards returns the port forwardings to the running containers, sorted by the protocol and the listen
+		// port. Every listen port is forwarded to a single container.
+		PortForwards() []PortForward
+	}
+)
+
+// The supported port forwarding protocols.
+const (
+	ForwardTCP = "tcp"
+	ForwardUDP = "udp"
+)
+
+// forwardListenPrefix is the prefix of the port forwarding label value.
+const forwardListenPrefix = "listen:"
+
+// PortForwards implements the [PortForwardsResolver] interface.
+func (s *State) PortForwards() []PortForward {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+
+	return slices.Clone(s.forwards)
+}
+
+// portForwards reads the port forwarding labels of the container. The target address is not set.
+func (s *State) portForwards(labels map[string]string, diag *diagnostics) []PortForward {
+	var forwards []PortForward
+
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		var proto, containerPort, found = s.forwardLabel(key)
+		if !found {
+			continue
+		}
+
+		var (
+			value           = strings.TrimSpace(labels[key])
+			listen, hasSpec = strings.CutPrefix(value, forwardListenPrefix)
+		)
+
+		port, portErr := strconv.ParseUint(containerPort, 10, 16)
+		if portErr != nil || port == 0 {
+			diag.warn("port forwarding", "the %s label has no valid container port, ignored", key)
+
+			continue
+		}
+
+		listenPort, listenErr := strconv.ParseUint(strings.TrimSpace(listen), 10, 16)
+		if !hasSpec || listenErr != nil || listenPort == 0 {
+			diag.warn("port forwarding", "the %s label value %q is not valid (expected %s<port>), ignored",
+				key, value, forwardListenPrefix,
+			)
+
+			continue
+		}
+
+		diag.ok("port forwarding", "the %s port %d is forwarded to the container port %d (set using the %s label)",
+			strings.ToUpper(proto), listenPort, port, key,
+		)
+
+		forwards = append(forwards, PortForward{
+			Protocol:   proto,
+			ListenPort: uint16(listenPort),
+			Target:     containerPort, // the container IP address is not known yet
+		})
+	}
+
+	return forwards
+}
+
+// forwardLabel returns the protocol and the container port (not validated) of the port forwarding label.
+func (s *State) forwardLabel(key string) (proto, containerPort string, _ bool) {
+	for _, p := range []string{ForwardTCP, ForwardUDP} {
+		for _, prefix := range s.labels.forward[p] {
+			if port, ok := strings.CutPrefix(key, prefix); ok {
+				return p, port, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// buildPortForwards returns the port forwardings to the listed (running) containers. If the listen port is claimed
+// by a few containers, the container with the lowest ID wins, and the rest are returned as ignored.
+func (s *State) buildPortForwards(list []container.Summary) (forwards, ignored []PortForward) {
+	var all = make([]PortForward, 0)
+
+	for _, c := range list {
+		if c.State != container.StateRunning {
+			continue
+		}
+
+		var fws = s.portForwards(c.Labels, nil)
+		if len(fws) == 0 {
+			continue
+		}
+
+		ipAddr, ok := s.forwardIPAddr(c)
+		if !ok {
+			continue
+		}
+
+		for _, fw := range fws {
+			fw.ContainerID, fw.Target = c.ID, net.JoinHostPort(ipAddr, fw.Target)
+
+			all = append(all, fw)
+		}
+	}
+
+	slices.SortFunc(all, func(a, b PortForward) int {
+		return cmp.Or(
+			cmp.Compare(a.Protocol, b.Protocol),
+			cmp.Compare(a.ListenPort, b.ListenPort),
+			cmp.Compare(a.ContainerID, b.ContainerID),
+		)
+	})
+
+	forwards = make([]PortForward, 0, len(all))
+
+	for _, fw := range all {
+		if n := len(forwards); n > 0 {
+			if last := forwards[n-1]; last.Protocol == fw.Protocol && last.ListenPort == fw.ListenPort {
+				ignored = append(ignored, fw)
+
+				continue
+			}
+		}
+
+		forwards = append(forwards, fw)
+	}
+
+	return forwards, ignored
+}
+
+// forwardIPAddr returns the IP address of the container in the network, set using the network label (or the
+// default one, or any other, if the container is not attached to it).
+func (s *State) forwardIPAddr(info container.Summary) (string, bool) {
+	if info.NetworkSettings == nil || len(info.NetworkSettings.Networks) == 0 {
+		return "", false
+	}
+
+	var netName = "bridge"
+
+	for _, wantNetLabel := range s.labels.network {
+		if v := strings.TrimSpace(info.Labels[wantNetLabel]); v != "" {
+			netName = v
+
+			break
+		}
+	}
+
+	if n, ok := info.NetworkSettings.Networks[netName]; ok && n != nil && n.IPAddress != "" {
+		return n.IPAddress, true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(info.NetworkSettings.Networks)) {
+		if n := info.NetworkSettings.Networks[name]; n != nil && n.IPAddress != "" {
+			return n.IPAddress, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_buildPortForwards(t *testing.T) {
+	t.Parallel()
+
+	var running = func(id string, labels map[string]string) container.Summary {
+		return container.Summary{
+			ID:              id,
+			State:           container.StateRunning,
+			Labels:          labels,
+			NetworkSettings: withNetworks("bridge"),
+		}
+	}
+
+	for name, tt := range map[string]struct {
+		giveOpts     []StateOption
+		giveList     []container.Summary
+		wantForwards []PortForward
+		wantIgnored  []PortForward
+	}{
+		"tcp and udp": {
+			giveList: []container.Summary{
+				running("db", map[string]string{"indocker.tcp.5432": "listen:15432"}),
+				running("mqtt", map[string]string{
+					"indocker.tcp.1883": " listen:11883 ",
+					"indocker.udp.1883": "listen:11883",
+					"indocker.host":     "mqtt",
+				}),
+			},
+			wantForwards: []PortForward{
+				{Protocol: "tcp", ListenPort: 11883, ContainerID: "mqtt", Target: "10.0.0.1:1883"},
+				{Protocol: "tcp", ListenPort: 15432, ContainerID: "db", Target: "10.0.0.1:5432"},
+				{Protocol: "udp", ListenPort: 11883, ContainerID: "mqtt", Target: "10.0.0.1:1883"},
+			},
+		},
+		"invalid labels": {
+			giveList: []container.Summary{
+				running("db", map[string]string{
+					"indocker.tcp.5432":  "15432",
+					"indocker.tcp.pg":    "listen:15432",
+					"indocker.tcp.0":     "listen:15432",
+					"indocker.udp.53":    "listen:99999",
+					"indocker.sctp.5432": "listen:15432",
+					"tcp.5432":           "listen:15432", // the bare labels are not honored
+				}),
+			},
+			wantForwards: []PortForward{},
+		},
+		"conflict": {
+			giveList: []container.Summary{
+				running("b", map[string]string{"indocker.tcp.6379": "listen:16379"}),
+				running("a", map[string]string{"indocker.tcp.6380": "listen:16379"}),
+			},
+			wantForwards: []PortForward{{Protocol: "tcp", ListenPort: 16379, ContainerID: "a", Target: "10.0.0.1:6380"}},
+			wantIgnored:  []PortForward{{Protocol: "tcp", ListenPort: 16379, ContainerID: "b", Target: "10.0.0.1:6379"}},
+		},
+		"not running": {
+			giveList: []container.Summary{{
+				ID:              "db",
+				State:           container.StateExited,
+				Labels:          map[string]string{"indocker.tcp.5432": "listen:15432"},
+				NetworkSettings: withNetworks("bridge"),
+			}},
+			wantForwards: []PortForward{},
+		},
+		"network label": {
+			giveList: []container.Summary{{
+				ID:     "db",
+				State:  container.StateRunning,
+				Labels: map[string]string{"indocker.tcp.5432": "listen:15432", "indocker.network": "backend"},
+				NetworkSettings: &container.NetworkSettingsSummary{Networks: map[string]*network.EndpointSettings{
+					"bridge":  {IPAddress: "10.0.0.1"},
+					"backend": {IPAddress: "10.0.1.1"},
+				}},
+			}},
+			wantForwards: []PortForward{{Protocol: "tcp", ListenPort: 15432, ContainerID: "db", Target: "10.0.1.1:5432"}},
+		},
+		"custom prefix": {
+			giveOpts: []StateOption{WithLabelPrefix("dev")},
+			giveList: []container.Summary{
+				running("db", map[string]string{"indocker.tcp.5432": "listen:15432", "dev.tcp.5432": "listen:25432"}),
+			},
+			wantForwards: []PortForward{{Protocol: "tcp", ListenPort: 25432, ContainerID: "db", Target: "10.0.0.1:5432"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			forwards, ignored := NewState(nil, tt.giveOpts...).buildPortForwards(tt.giveList)
+
+			assert.Equal(t, tt.wantForwards, forwards)
+			assert.Equal(t, tt.wantIgnored, ignored)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// hostnameRegex matches the valid hostname (one or more DNS labels).
+//
+//nolint:gochecknoglobals
+var hostnameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// IsValidHostname returns true if the (lowercased) hostname consists of valid DNS labels (up to 63 characters
+// each, 253 in total).
+func IsValidHostname(hostname string) bool {
+	const maxLabelLen, maxLen = 63, 253
+
+	if len(hostname) > maxLen || !hostnameRegex.MatchString(hostname) {
+		return false
+	}
+
+	for label := range strings.SplitSeq(hostname, ".") {
+		if len(label) > maxLabelLen {
+			return false
+		}
+	}
+
+	return true
+}
+
+// the labels, set by the docker compose.
+const (
+	composeServiceLabel         = "com.docker.compose.service"
+	composeProjectLabel         = "com.docker.compose.project"
+	composeContainerNumberLabel = "com.docker.compose.container-number"
+)
+
+// hostnameTemplateData is the container metadata, available in the host label templates (e.g.
+// "{{.Compose.Service}}-{{.Compose.Project}}").
+type hostnameTemplateData struct {
+	ID      string            // the short container ID
+	Name    string            // the container name (without the leading slash)
+	Image   string            // the image name
+	Labels  map[string]string // the container labels
+	Replica string            // the compose container number (e.g. "1"), if any
+	Compose struct {
+		Service string // the compose service name, if any
+		Project string // the compose project name, if any
+	}
+}
+
+// isHostnameTemplate returns true if the host label value is a template.
+func isHostnameTemplate(v string) bool { return strings.Contains(v, "{{") }
+
+// renderHostnameTemplate renders the host label template using the container metadata. Missing labels are
+// reported as errors (instead of rendering "<no value>").
+func renderHostnameTemplate(tpl string, info container.Summary) (string, error) {
+	t, err := template.New("host").Option("missingkey=error").Parse(tpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var data = hostnameTemplateData{
+		ID:      shortID(info.ID),
+		Image:   info.Image,
+		Labels:  info.Labels,
+		Replica: info.Labels[composeContainerNumberLabel],
+	}
+
+	if len(info.Names) > 0 {
+		data.Name = strings.TrimPrefix(info.Names[0], "/")
+	}
+
+	data.Compose.Service = info.Labels[composeServiceLabel]
+	data.Compose.Project = info.Labels[composeProjectLabel]
+
+	var buf strings.Builder
+
+	if err = t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render the template: %w", err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,36 @@
+package docker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+func TestIsValidHostname(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]bool{
+		"app":                           true,
+		"web-1.feature-x":               true,
+		"1st":                           true,
+		strings.Repeat("a", 63):         true,
+		strings.Repeat("a", 64):         false,
+		strings.Repeat("a.", 127) + "a": false,
+		"":                              false,
+		"-app":                          false,
+		"app-":                          false,
+		"my_app":                        false,
+		"App":                           false,
+		"app..foo":                      false,
+		"<no value>":                    false,
+	} {
+		t.Run(give, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, want, docker.IsValidHostname(give))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package docker
+
+import "time"
+
+type (
+	// RouteSource is a source of the route (where the route comes from).
+	RouteSource string
+
+	// RouteOptions are the additional route target options.
+	RouteOptions struct {
+		Source        RouteSource       // where the route comes from
+		PreserveHost  bool              // pass the original Host header to the upstream
+		Headers       map[string]string // additional request headers, sent to the upstream
+		PathPrefix    string            // route only the requests with this path prefix (empty = any path)
+		StripPrefixes []string          // path prefixes, stripped from the request path before passing it upstream
+		Autostart     bool              // the stopped container is started on the first request (scale-to-zero)
+		Sleeping      bool              // the autostart container is stopped
+		Starting      bool              // the autostart container is being started right now
+		IdleTimeout   time.Duration     // the autostart container is stopped after this period of inactivity
+		Passthrough   bool              // the raw TLS connections are passed to the upstream (it terminates TLS)
+		TLS           UpstreamTLS       // the TLS settings, used to connect to the https (or grpcs) upstream
+	}
+
+	// UpstreamTLS are the TLS settings, used to connect to the upstream. The zero value means the defaults (the
+	// upstream certificate is not verified, for backward compatibility). Without the server name, the upstream
+	// certificate is verified against the upstream (container) IP address.
+	UpstreamTLS struct {
+		Verify     bool   // verify the upstream certificate
+		CAFile     string // the CA bundle (PEM) to verify the upstream certificate with (system roots, if empty)
+		ServerName string // the server name (SNI), sent to the upstream and used to verify its certificate
+		CertFile   string // the client certificate (PEM), presented to the upstream (mTLS)
+		KeyFile    string // the client certificate key (PEM)
+	}
+
+	RouteOptionsResolver interface {
+		// RouteOptions returns the options of the route target with the given ID.
+		RouteOptions(targetID string) (RouteOptions, bool)
+	}
+)
+
+const (
+	RouteSourceDocker   RouteSource = "docker" // discovered using the docker labels
+	RouteSourceFile     RouteSource = "file"   // defined in the routes file
+	RouteSourceHTTP     RouteSource = "http"   // fetched from the remote (HTTP) endpoint
+	RouteSourceAPI      RouteSource = "api"    // added at runtime using the monitor API
+	RouteSourceHostPort RouteSource = "host"   // the "<port>.indocker.app" shortcut to the port on the host
+)
+
+// IsZero returns true if the default TLS settings are used.
+func (t UpstreamTLS) IsZero() bool { return t == UpstreamTLS{} }
@@ -0,0 +1,28 @@
+package docker
+
+// The supported upstream (container) schemes.
+const (
+	SchemeHTTP  = "http"  // HTTP/1.1 (default)
+	SchemeHTTPS = "https" // HTTP/1.1 or HTTP/2 over TLS
+	SchemeH2C   = "h2c"   // HTTP/2 without TLS (cleartext)
+	SchemeGRPC  = "grpc"  // gRPC (HTTP/2 without TLS)
+	SchemeGRPCS = "grpcs" // gRPC over TLS
+)
+
+// IsSupportedScheme returns true if the upstream scheme is supported by the proxy.
+func IsSupportedScheme(scheme string) bool {
+	switch scheme {
+	case SchemeHTTP, SchemeHTTPS, SchemeH2C, SchemeGRPC, SchemeGRPCS:
+		return true
+	}
+
+	return false
+}
+
+// IsTLSScheme returns true if the upstream with the scheme must be reached over TLS.
+func IsTLSScheme(scheme string) bool { return scheme == SchemeHTTPS || scheme == SchemeGRPCS }
+
+// IsHTTP2Scheme returns true if the upstream with the scheme speaks HTTP/2 only.
+func IsHTTP2Scheme(scheme string) bool {
+	return scheme == SchemeH2C || scheme == SchemeGRPC || scheme == SchemeGRPCS
+}
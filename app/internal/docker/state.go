@@ -2,19 +2,23 @@ package docker
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"maps"
+	"net"
 	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
 	dc "github.com/docker/docker/client"
+	"go.uber.org/zap"
 )
 
 type (
@@ -25,6 +29,12 @@ type (
 		SubscribeForRoutingUpdates() (sub <-chan RoutesMap, stop func())
 	}
 
+	RemovedRouteResolver interface {
+		// RouteRemovedAt returns the time when the hostname was removed from the routing table (e.g. the container
+		// is being recreated). It returns false if the hostname is routed, or was never routed.
+		RouteRemovedAt(hostname string) (time.Time, bool)
+	}
+
 	RoutingURLResolver interface {
 		URLToContainerByHostname(hostname string) (ContainerMap, bool)
 	}
@@ -32,26 +42,119 @@ type (
 	AllContainerURLsResolver interface {
 		AllContainerURLs() RoutesMap
 	}
+
+	RoutedContainerResolver interface {
+		RoutedContainerID(idOrPrefix string) (string, bool)
+	}
+
+	ManagedContainerChecker interface {
+		IsManagedContainer(info container.InspectResponse) bool
+	}
+
+	StateUpdater interface {
+		Update(ctx context.Context) error
+	}
+)
+
+var (
+	// ErrContainerNotRouted is returned when the requested container is not found or not routed by the state.
+	ErrContainerNotRouted = errors.New("container not found (or not routed)")
+
+	// ErrContainerNotFound is returned when the requested container is not found.
+	ErrContainerNotFound = errors.New("container not found")
+
+	// ErrContainerNotManaged is returned when the requested container is not found or has no indocker labels.
+	ErrContainerNotManaged = errors.New("container not found (or not managed by indocker)")
 )
 
 type (
 	State struct {
-		dc *dc.Client
-
-		routesMu sync.Mutex // protects routes
-		routes   RoutesMap  // containers routing, map[hostname]url.URL
+		dc             *dc.Client
+		log            *zap.Logger
+		conflictPolicy ConflictPolicy
+		virtualHosts   bool          // honor the nginx-proxy environment variables (VIRTUAL_HOST, etc.)
+		traefikLabels  bool          // honor the Traefik labels (traefik.http.routers.<name>.rule, etc.)
+		networkDomains bool          // honor the domain labels of the networks (route as "<service>.<domain>")
+		labelPrefix    string        // the routing labels prefix (namespace)
+		strictLabels   bool          // honor only the prefixed routing labels
+		labels         routingLabels // the routing label names (built using the prefix and the strict mode)
+
+		envMu    sync.Mutex                   // protects envCache
+		envCache map[string]map[string]string // the nginx-proxy environment variables, map[container_id]vars
+
+		domainsMu sync.Mutex        // protects domains
+		domains   map[string]string // the domains, set using the network labels, map[network_name]domain
+
+		wakeMu     sync.Mutex           // protects waking
+		waking     map[string]*wakeup   // the containers, being started right now, map[container_id]process
+		activityMu sync.Mutex           // protects activity
+		activity   map[string]time.Time // the last request time of the autostart containers, map[container_id]time
+
+		routesMu  sync.Mutex              // protects the fields below
+		routes    RoutesMap               // containers routing, map[hostname]url.URL
+		options   map[string]RouteOptions // the route targets options, map[container_id]RouteOptions
+		conflicts []RouteConflict         // detected route conflicts, sorted by the hostname
+		forwards  []PortForward           // the port forwardings, sorted by the protocol and the listen port
 
 		routeChangesSubsMu sync.Mutex                       // protects subs
 		routeChangesSubs   map[chan RoutesMap]chan struct{} // map[subscription]stop_channel
 	}
+
+	StateOption func(*State)
+
+	// routeKey identifies the route (the hostname and the path prefix).
+	routeKey struct{ hostname, pathPrefix string }
 )
 
-func NewState(dc *dc.Client) *State {
-	return &State{
+// WithStateLogger sets the logger, used to report the route conflicts.
+func WithStateLogger(log *zap.Logger) StateOption { return func(s *State) { s.log = log } }
+
+// WithConflictPolicy sets the policy, used to resolve the route conflicts.
+func WithConflictPolicy(p ConflictPolicy) StateOption { return func(s *State) { s.conflictPolicy = p } }
+
+// WithVirtualHostEnv enables the nginx-proxy compatibility mode: containers without indocker labels are routed using
+// the VIRTUAL_HOST, VIRTUAL_PORT, VIRTUAL_PROTO and VIRTUAL_PATH environment variables.
+func WithVirtualHostEnv(enabled bool) StateOption { return func(s *State) { s.virtualHosts = enabled } }
+
+// WithLabelPrefix sets the routing labels prefix (namespace), "indocker." by default. Using different prefixes, a few
+// instances can share the same docker daemon, each one routing only its own containers.
+func WithLabelPrefix(prefix string) StateOption { return func(s *State) { s.labelPrefix = prefix } }
+
+// WithStrictLabels disables the bare (not prefixed) routing labels, like "host" or "port", which may be set by
+// unrelated images.
+func WithStrictLabels(enabled bool) StateOption { return func(s *State) { s.strictLabels = enabled } }
+
+// WithTraefikLabels enables the Traefik compatibility mode: containers without indocker labels are routed using the
+// common subset of the Traefik labels (see traefikRoute for details).
+func WithTraefikLabels(enabled bool) StateOption { return func(s *State) { s.traefikLabels = enabled } }
+
+// WithNetworkDomains enables (the default) or disables the routing of the containers, attached to the networks with
+// the domain label, as "<service>.<domain>" (see domainHosts for details).
+func WithNetworkDomains(enabled bool) StateOption {
+	return func(s *State) { s.networkDomains = enabled }
+}
+
+func NewState(dc *dc.Client, opts ...StateOption) *State {
+	var s = &State{
 		dc:               dc,
+		log:              zap.NewNop(),
+		conflictPolicy:   ConflictPolicyMerge,
+		networkDomains:   true,
 		routes:           make(RoutesMap),
+		options:          make(map[string]RouteOptions),
+		envCache:         make(map[string]map[string]string),
+		waking:           make(map[string]*wakeup),
+		activity:         make(map[string]time.Time),
 		routeChangesSubs: make(map[chan RoutesMap]chan struct{}),
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.labels = newRoutingLabels(s.labelPrefix, s.strictLabels)
+
+	return s
 }
 
 // StartAutoUpdate starts an automatic update of the state of running containers, using the docker events API.
@@ -107,12 +210,11 @@ func (s *State) StartAutoUpdate(ctx context.Context) (stop func()) { //nolint:go
 }
 
 // Update updates the state of running containers immediately. It returns an error if something went wrong.
-func (s *State) Update(ctx context.Context) error { //nolint:gocyclo
+func (s *State) Update(ctx context.Context) error { //nolint:funlen
 	var filter = filters.NewArgs()
 
-	// we need to filter only certain statuses (alive containers)
-	// all statuses = (created|restarting|running|removing|paused|exited|dead)
-	for _, status := range []string{"created", "restarting", "running", "removing", "paused"} {
+	// we need to filter only certain statuses (alive containers, and the stopped ones - for the autostart)
+	for _, status := range append(slices.Clone(aliveContainerStatuses), sleepingContainerStatuses...) {
 		filter.Add("status", status)
 	}
 
@@ -125,58 +227,177 @@ func (s *State) Update(ctx context.Context) error { //nolint:gocyclo
 		return listErr
 	}
 
-	var newRoutes = make(RoutesMap, len(list))
+	// the stopped containers are routed only if they have the autostart label
+	list = slices.DeleteFunc(list, func(c container.Summary) bool {
+		autostart, _ := s.autostartOptions(c.Labels, nil)
+
+		return !slices.Contains(aliveContainerStatuses, c.State) && !autostart
+	})
+
+	// get the domains, set using the network labels (on failure, the previous ones are kept, so the rest of the
+	// routing is not affected)
+	if domains, domainsErr := s.listNetworkDomains(ctx); domainsErr == nil {
+		s.domainsMu.Lock()
+		s.domains = domains
+		s.domainsMu.Unlock()
+	} else {
+		s.log.Warn("Failed to list the networks, the previous network domains are used", zap.Error(domainsErr))
+	}
+
+	var (
+		envs        = s.virtualHostEnvs(ctx, list)
+		candidates  = make(map[routeKey][]routeCandidate, len(list))
+		routingOpts = make(map[string]RouteOptions, len(list)) // map[container_id]options
+	)
 
 	for _, listedContainer := range list {
 		// set the routing info, if possible
-		if scheme, hostname, ipAddr, port, found := s.buildRouteToContainer(listedContainer); found {
-			if scheme != "" && hostname != "" && ipAddr != "" && port != 0 { // an additional check
-				var u = url.URL{
-					Scheme: scheme,
-					Host:   fmt.Sprintf("%s:%d", ipAddr, port),
+		if route, found := s.buildRouteToContainer(listedContainer, envs[listedContainer.ID], nil); found {
+			if route.scheme != "" && route.ipAddr != "" && route.port != 0 { // an additional check
+				var opts = route.options()
+
+				if route.autostart {
+					opts.Starting = s.isWaking(listedContainer.ID)
 				}
 
-				if _, ok := newRoutes[hostname]; !ok {
-					newRoutes[hostname] = make(map[string]url.URL)
+				routingOpts[listedContainer.ID] = opts
+
+				for _, hostname := range route.hosts {
+					var key = routeKey{hostname: hostname, pathPrefix: route.pathPrefix}
+
+					candidates[key] = append(candidates[key], newRouteCandidate(listedContainer, route.url(), s.labels.priority))
 				}
+			}
+		}
+	}
+
+	var (
+		newRoutes    = make(RoutesMap, len(candidates))
+		newOptions   = make(map[string]RouteOptions, len(candidates))
+		newConflicts = make([]RouteConflict, 0)
+	)
+
+	for key, claimed := range candidates {
+		winners, conflict := resolveConflict(s.conflictPolicy, key.hostname, claimed)
+		if conflict != nil {
+			newConflicts = append(newConflicts, *conflict)
+		}
 
-				newRoutes[hostname][listedContainer.ID] = u
+		for _, w := range winners {
+			if _, ok := newRoutes[key.hostname]; !ok {
+				newRoutes[key.hostname] = make(map[string]url.URL)
 			}
+
+			newRoutes[key.hostname][w.id], newOptions[w.id] = w.url, routingOpts[w.id]
 		}
 	}
 
-	var routesUpdated bool
+	slices.SortFunc(newConflicts, func(a, b RouteConflict) int { return strings.Compare(a.Hostname, b.Hostname) })
+
+	var (
+		newForwards, ignoredForwards                     = s.buildPortForwards(list)
+		routesUpdated, conflictsUpdated, forwardsUpdated bool
+	)
 
 	s.routesMu.Lock()
-	routesUpdated = !reflect.DeepEqual(s.routes, newRoutes) // check if the routes have been updated
-	clear(s.routes)                                         // care about the memory
-	s.routes = newRoutes                                    // update the routes
+	routesUpdated = !reflect.DeepEqual(s.routes, newRoutes) || !reflect.DeepEqual(s.options, newOptions)
+	conflictsUpdated = !reflect.DeepEqual(s.conflicts, newConflicts)
+	forwardsUpdated = !reflect.DeepEqual(s.forwards, newForwards)
+	clear(s.routes) // care about the memory
+	s.routes, s.options, s.conflicts, s.forwards = newRoutes, newOptions, newConflicts, newForwards
 	s.routesMu.Unlock()
 
-	if routesUpdated {
-		s.routeChangesSubsMu.Lock()
+	if forwardsUpdated {
+		for _, fw := range ignoredForwards {
+			s.log.Warn("Port forwarding conflict detected, the port is forwarded to another container",
+				zap.String("protocol", fw.Protocol),
+				zap.Uint16("port", fw.ListenPort),
+				zap.String("ignored container", fw.ContainerID),
+			)
+		}
+	}
 
-		for sub, stop := range s.routeChangesSubs {
-			go func(sub chan<- RoutesMap, stop <-chan struct{}) {
-				select { // first, check if the subscription and context are still alive
-				case <-stop: // is the subscription stopped?
-				case <-ctx.Done(): // is the context done?
-				default:
-					select { // then, notify the subscribers
-					case <-stop: // is the subscription stopped?
-					case <-ctx.Done(): //  is the context done?
-					case sub <- maps.Clone(newRoutes): // notify the subscriber
-					}
-				}
-			}(sub, stop)
+	if conflictsUpdated {
+		for _, conflict := range newConflicts {
+			s.log.Warn("Route conflict detected",
+				zap.String("hostname", conflict.Hostname),
+				zap.String("policy", string(conflict.Policy)),
+				zap.Strings("containers", conflict.Containers),
+				zap.Strings("routed to", conflict.Winners),
+			)
 		}
+	}
 
-		s.routeChangesSubsMu.Unlock()
+	// conflicts and port forwardings are a part of the routing info for the subscribers
+	if routesUpdated || conflictsUpdated || forwardsUpdated {
+		s.notifySubscribers(ctx, newRoutes)
 	}
 
 	return nil
 }
 
+// virtualHostEnvs returns the nginx-proxy environment variables of the listed containers without indocker labels
+// (the container list has no environment, so the containers are inspected). Since the container environment can't
+// be changed, the results are cached. It returns nil if the nginx-proxy compatibility mode is disabled.
+func (s *State) virtualHostEnvs(ctx context.Context, list []container.Summary) map[string]map[string]string {
+	if !s.virtualHosts {
+		return nil
+	}
+
+	s.envMu.Lock()
+	defer s.envMu.Unlock()
+
+	var envs = make(map[string]map[string]string, len(list))
+
+	for _, c := range list {
+		if s.hasRoutingLabels(c.Labels) {
+			continue // labels take precedence
+		}
+
+		if vars, cached := s.envCache[c.ID]; cached {
+			envs[c.ID] = vars
+
+			continue
+		}
+
+		inspected, err := s.dc.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			s.log.Debug("Failed to inspect the container", zap.String("id", c.ID), zap.Error(err))
+
+			continue // will be retried on the next update
+		}
+
+		if inspected.Config != nil {
+			envs[c.ID] = virtualHostVars(inspected.Config.Env)
+		}
+	}
+
+	s.envCache = envs // drop the removed containers
+
+	return envs
+}
+
+// notifySubscribers sends the routing to all subscribers (asynchronously).
+func (s *State) notifySubscribers(ctx context.Context, routes RoutesMap) {
+	s.routeChangesSubsMu.Lock()
+	defer s.routeChangesSubsMu.Unlock()
+
+	for sub, stop := range s.routeChangesSubs {
+		go func(sub chan<- RoutesMap, stop <-chan struct{}) {
+			select { // first, check if the subscription and context are still alive
+			case <-stop: // is the subscription stopped?
+			case <-ctx.Done(): // is the context done?
+			default:
+				select { // then, notify the subscribers
+				case <-stop: // is the subscription stopped?
+				case <-ctx.Done(): //  is the context done?
+				case sub <- maps.Clone(routes): // notify the subscriber
+				}
+			}
+		}(sub, stop)
+	}
+}
+
 // SubscribeForRoutingUpdates will return a subscription channel and a stop function. The subscription channel will
 // receive a message when the routing info is updated. The stop function will stop the subscription.
 // The subscription channel will be closed when the stop function is called.
@@ -242,82 +463,284 @@ func (s *State) AllContainerURLs() (routes RoutesMap) { // map[hostname]map[cont
 	return
 }
 
-//nolint:gochecknoglobals
-var (
-	hostLabels        = []string{"indocker.host", "indocker.hostname", "host", "hostname"}
-	schemeLabels      = []string{"indocker.scheme", "indocker.schema", "scheme", "schema"}
-	portLabels        = []string{"indocker.port", "port"}
-	networkNameLabels = []string{"indocker.network", "indocker.net", "network", "net"}
-)
+// RouteOptions returns the options of the route target (container) with the given ID.
+func (s *State) RouteOptions(targetID string) (RouteOptions, bool) {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
 
-// buildRouteToContainer returns the routing info to the container, if possible. It returns false if the container
-// does not have the required labels or the network settings.
-func (s *State) buildRouteToContainer(info container.Summary) ( //nolint:funlen,gocognit,gocyclo
-	scheme, host, ipAddr string, port uint16, found bool,
-) {
-	scheme, port = "http", uint16(80) //nolint:mnd // defaults
-
-	// determine the host
-	for _, wantHostLabel := range hostLabels {
-		if v, ok := info.Labels[wantHostLabel]; ok {
-			v = strings.ToLower(strings.TrimSpace(v))
+	opts, ok := s.options[targetID]
 
-			if v == "" {
-				continue
-			}
+	return opts, ok
+}
 
-			// drop the ".indocker.app" if it exists
-			if withoutPostfix, cut := strings.CutSuffix(v, ".indocker.app"); cut {
-				host = withoutPostfix
-			} else {
-				host = v
+// RouteConflicts returns the detected route conflicts, sorted by the hostname.
+func (s *State) RouteConflicts() []RouteConflict {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+
+	return slices.Clone(s.conflicts)
+}
+
+// RoutedContainerID returns the full ID of the container, routed by the state, using its full or short ID (prefix).
+// It returns false if the container is not routed or the short ID is ambiguous.
+func (s *State) RoutedContainerID(idOrPrefix string) (string, bool) {
+	idOrPrefix = strings.ToLower(strings.TrimSpace(idOrPrefix))
+
+	if idOrPrefix == "" {
+		return "", false
+	}
+
+	var found string
+
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+
+	for _, containers := range s.routes {
+		for id := range containers {
+			if id == idOrPrefix {
+				return id, true
 			}
 
-			break
+			if strings.HasPrefix(id, idOrPrefix) {
+				if found != "" && found != id {
+					return "", false // ambiguous short ID
+				}
+
+				found = id
+			}
 		}
 	}
 
-	// determine the scheme
-	for _, wantSchemeLabel := range schemeLabels {
-		if v, ok := info.Labels[wantSchemeLabel]; ok {
-			v = strings.ToLower(strings.TrimSpace(v))
+	return found, found != ""
+}
 
-			if v == "" {
-				continue
-			} else {
-				if v == "https" {
-					port = 443 // in case of https, set the default port to 443
-				}
+// IsManagedContainer returns true if the container is (or can be, once it is running) routed by indocker: it has the
+// host label (or the Traefik router rule, or the VIRTUAL_HOST environment variable, if the compatibility modes are
+// enabled), or is attached to the network with the domain label. The same sources are used to build the routes.
+func (s *State) IsManagedContainer(info container.InspectResponse) bool {
+	if info.Config == nil {
+		return false
+	}
+
+	if s.hasRoutingLabels(info.Config.Labels) {
+		return true
+	}
+
+	if s.virtualHosts && strings.TrimSpace(virtualHostVars(info.Config.Env)[virtualHostEnv]) != "" {
+		return true
+	}
 
-				scheme = v
+	if info.NetworkSettings != nil {
+		s.domainsMu.Lock()
+		defer s.domainsMu.Unlock()
+
+		for name := range info.NetworkSettings.Networks {
+			if _, ok := s.domains[name]; ok {
+				return true
 			}
+		}
+	}
 
-			break
+	return false
+}
+
+// hasRoutingLabels returns true if the container labels contain the host label (or the Traefik router rule, if the
+// Traefik compatibility mode is enabled).
+func (s *State) hasRoutingLabels(labels map[string]string) bool {
+	return s.hasHostLabel(labels) || (s.traefikLabels && isTraefikContainer(labels))
+}
+
+// hasHostLabel returns true if the container labels contain the (non-empty) indocker host label.
+func (s *State) hasHostLabel(labels map[string]string) bool {
+	for _, wantHostLabel := range s.labels.host {
+		if v, ok := labels[wantHostLabel]; ok && strings.TrimSpace(v) != "" {
+			return true
 		}
 	}
 
-	// determine the port
-	for _, wantPortLabel := range portLabels {
-		if v, ok := info.Labels[wantPortLabel]; ok {
-			v = strings.TrimSpace(v)
+	return false
+}
 
-			if v == "" {
-				continue
-			}
+// aliveContainerStatuses is a list of container statuses, which are considered as alive (routable).
+// all statuses = (created|restarting|running|removing|paused|exited|dead).
+//
+//nolint:gochecknoglobals
+var aliveContainerStatuses = []string{"created", "restarting", "running", "removing", "paused"}
 
-			// parse the port
-			if parsed, parseErr := strconv.ParseUint(v, 10, 16); parseErr == nil {
-				port = uint16(parsed)
-			}
+// sleepingContainerStatuses is a list of container statuses, which are considered as stopped (the containers with
+// the autostart label are routed anyway, and started on the first request).
+//
+//nolint:gochecknoglobals
+var sleepingContainerStatuses = []string{"created", "exited"}
+
+// DefaultLabelPrefix is the default prefix (namespace) of the routing labels.
+const DefaultLabelPrefix = "indocker."
+
+// routingLabels are the names of the labels, used to configure the routing to the container (in the order of
+// precedence).
+type routingLabels struct {
+	host, scheme, port, network, path, priority []string
+	domain                                      []string // the network labels
+	autostart, idleTimeout                      []string
+	tlsPassthrough                              []string
+	tlsVerify, tlsCA, tlsServerName             []string            // the upstream TLS settings
+	tlsCert, tlsKey                             []string            // the client certificate (mTLS)
+	forward                                     map[string][]string // the port forwarding label prefixes by protocol
+}
 
-			break
+// newRoutingLabels returns the routing label names with the given prefix (e.g. "indocker.host"). Unless the strict
+// mode is enabled, the bare label names (e.g. "host") are honored too.
+func newRoutingLabels(prefix string, strict bool) routingLabels {
+	if prefix == "" {
+		prefix = DefaultLabelPrefix
+	} else if !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	var names = func(bare bool, suffixes ...string) []string {
+		var list = make([]string, 0, len(suffixes)*2) //nolint:mnd
+
+		for _, suffix := range suffixes {
+			list = append(list, prefix+suffix)
+		}
+
+		if bare && !strict {
+			list = append(list, suffixes...)
+		}
+
+		return list
+	}
+
+	return routingLabels{
+		host:     names(true, "host", "hostname"),
+		scheme:   names(true, "scheme", "schema"),
+		port:     names(true, "port"),
+		network:  names(true, "network", "net"),
+		path:     names(false, "path"),
+		priority: names(false, "priority"),
+		domain:   names(false, "domain"),
+
+		autostart:   names(false, "autostart"),
+		idleTimeout: names(false, "idle-timeout"),
+
+		tlsPassthrough: names(false, "tls.passthrough"),
+		tlsVerify:      names(false, "tls.verify"),
+		tlsCA:          names(false, "tls.ca"),
+		tlsServerName:  names(false, "tls.server-name"),
+		tlsCert:        names(false, "tls.cert"),
+		tlsKey:         names(false, "tls.key"),
+		forward: map[string][]string{
+			ForwardTCP: names(false, ForwardTCP+"."),
+			ForwardUDP: names(false, ForwardUDP+"."),
+		},
+	}
+}
+
+// the nginx-proxy (jwilder/nginx-proxy) compatible environment variables.
+const (
+	virtualHostEnv  = "VIRTUAL_HOST"
+	virtualPortEnv  = "VIRTUAL_PORT"
+	virtualProtoEnv = "VIRTUAL_PROTO"
+	virtualPathEnv  = "VIRTUAL_PATH"
+)
+
+// virtualHostVars extracts the nginx-proxy environment variables from the container environment ("KEY=value").
+func virtualHostVars(env []string) map[string]string {
+	var vars map[string]string
+
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, "VIRTUAL_") {
+			switch k {
+			case virtualHostEnv, virtualPortEnv, virtualProtoEnv, virtualPathEnv:
+				if vars == nil {
+					vars = make(map[string]string, 4) //nolint:mnd
+				}
+
+				vars[k] = v
+			}
 		}
 	}
 
-	var netName = "bridge" // defaults
+	return vars
+}
+
+// containerRoute is the routing info to the container.
+type containerRoute struct {
+	scheme        string
+	hosts         []string // one or more hostnames (without the ".indocker.app" suffix)
+	ipAddr        string
+	port          uint16
+	pathPrefix    string            // the path prefix (empty = any path)
+	network       string            // the network to use (empty = the default one, or set using the labels)
+	autostart     bool              // start the stopped container on the first request
+	sleeping      bool              // the container is stopped (the ipAddr is the container name in this case)
+	idleTimeout   time.Duration     // stop the autostart container after this period of inactivity (zero = never)
+	preserveHost  bool              // pass the original Host header to the upstream
+	headers       map[string]string // additional request headers
+	stripPrefixes []string          // path prefixes to strip before passing the request to the upstream
+	passthrough   bool              // the container terminates TLS itself, the raw TLS stream is passed to it
+	upstreamTLS   UpstreamTLS       // the TLS settings, used to connect to the upstream
+}
+
+// options returns the route options.
+func (r containerRoute) options() RouteOptions {
+	return RouteOptions{
+		Source:        RouteSourceDocker,
+		PreserveHost:  r.preserveHost,
+		Headers:       r.headers,
+		PathPrefix:    r.pathPrefix,
+		StripPrefixes: r.stripPrefixes,
+		Autostart:     r.autostart,
+		Sleeping:      r.sleeping,
+		IdleTimeout:   r.idleTimeout,
+		Passthrough:   r.passthrough,
+		TLS:           r.upstreamTLS,
+	}
+}
+
+// url returns the upstream URL.
+func (r containerRoute) url() url.URL {
+	return url.URL{Scheme: r.scheme, Host: net.JoinHostPort(r.ipAddr, strconv.Itoa(int(r.port)))}
+}
+
+// buildRouteToContainer returns the routing info to the container, if possible. It returns false if the container
+// does not have the required labels (or the nginx-proxy environment variables, if they are passed, or the Traefik
+// labels, if the Traefik compatibility mode is enabled) or the network settings. Every decision is recorded to the
+// diagnostics (it's ok to pass nil, if the diagnostics are not needed).
+func (s *State) buildRouteToContainer( //nolint:funlen,gocognit
+	info container.Summary,
+	env map[string]string,
+	diag *diagnostics,
+) (route containerRoute, found bool) {
+	// check the container state
+	if info.State == container.StateRunning {
+		diag.ok("container state", "the container is running")
+	} else {
+		diag.warn("container state", "the container is not running (state: %s)", info.State)
+	}
+
+	var netKeys = s.labels.network
+
+	// the Traefik labels are used only if the container has no indocker labels
+	if s.traefikLabels && !s.hasHostLabel(info.Labels) && isTraefikContainer(info.Labels) {
+		route, netKeys = traefikRoute(info, diag), []string{traefikNetworkLabel}
+	} else {
+		route = s.labelsRoute(info, env, diag)
+	}
+
+	if len(route.hosts) > 0 {
+		route.autostart, route.idleTimeout = s.autostartOptions(info.Labels, diag)
+		route.upstreamTLS = s.upstreamTLS(info.Labels, route.scheme, diag)
+	}
+
+	var netName, netLabel = "bridge", "" // defaults
+
+	if route.network != "" {
+		netName = route.network // the network, the route is built for (e.g. the network with the domain label)
+	}
 
 	// determine the network name
-	for _, wantNetLabel := range networkNameLabels {
+	for _, wantNetLabel := range netKeys {
 		if v, ok := info.Labels[wantNetLabel]; ok {
 			v = strings.TrimSpace(v)
 
@@ -325,14 +748,14 @@ func (s *State) buildRouteToContainer(info container.Summary) ( //nolint:funlen,
 				continue
 			}
 
-			netName = v
+			netName, netLabel = v, wantNetLabel
 
 			break
 		}
 	}
 
 	// only if the host is set
-	if host != "" { //nolint:nestif
+	if len(route.hosts) > 0 { //nolint:nestif
 		// check if the container has the networks at all
 		if info.NetworkSettings != nil && len(info.NetworkSettings.Networks) > 0 {
 			var net *network.EndpointSettings
@@ -340,11 +763,24 @@ func (s *State) buildRouteToContainer(info container.Summary) ( //nolint:funlen,
 			// check if the container has the required network
 			if namedNet, ok := info.NetworkSettings.Networks[netName]; ok {
 				net = namedNet // pick it
+
+				diag.ok("network", "the %q network is used", netName)
 			} else {
 				// if the container has multiple networks, but the required one is not found - pick a random one
-				for _, rndNet := range info.NetworkSettings.Networks {
+				for rndName, rndNet := range info.NetworkSettings.Networks {
 					net = rndNet
 
+					if netLabel != "" {
+						diag.warn("network", "the %q network (set using the %s label) is not attached to the "+
+							"container, a random network %q is used instead (typo in the network name?)",
+							netName, netLabel, rndName,
+						)
+					} else {
+						diag.info("network", "the default %q network is not attached to the container, the %q "+
+							"network is used instead", netName, rndName,
+						)
+					}
+
 					break
 				}
 			}
@@ -352,13 +788,275 @@ func (s *State) buildRouteToContainer(info container.Summary) ( //nolint:funlen,
 			// and only if the network is set
 			if net != nil && net.IPAddress != "" {
 				// we can determine the IP address of the container
-				ipAddr = net.IPAddress
+				route.ipAddr = net.IPAddress
+
+				diag.ok("ip address", "the container IP address is %s", route.ipAddr)
 
 				// and return the result
-				return scheme, host, ipAddr, port, true
+				return route, true
+			}
+
+			// the stopped container has no IP address, but it will be started on the first request
+			if route.autostart && slices.Contains(sleepingContainerStatuses, info.State) && len(info.Names) > 0 {
+				route.ipAddr, route.sleeping = strings.TrimPrefix(info.Names[0], "/"), true
+
+				diag.info("ip address", "the container is stopped, and will be started on the first request")
+
+				return route, true
+			}
+
+			diag.fail("ip address", "the container has no IP address in the selected network")
+		} else {
+			diag.fail("network", "the container is not attached to any network")
+		}
+	}
+
+	return containerRoute{}, false
+}
+
+// labelsRoute determines the hostnames, scheme, port and path prefix of the route to the container using the
+// indocker labels (or the nginx-proxy environment variables, if they are passed).
+func (s *State) labelsRoute( //nolint:funlen,gocognit,gocyclo
+	info container.Summary,
+	env map[string]string,
+	diag *diagnostics,
+) (route containerRoute) {
+	route.scheme, route.port = "http", uint16(80) //nolint:mnd // defaults
+
+	var (
+		values                                = info.Labels
+		hostKeys, schemeKeys, portKeys, paths = s.labels.host, s.labels.scheme, s.labels.port, s.labels.path
+	)
+
+	// the nginx-proxy environment variables are used only if the container has no indocker labels
+	if len(env) > 0 && !s.hasHostLabel(info.Labels) {
+		values = maps.Clone(info.Labels)
+
+		if values == nil {
+			values = make(map[string]string, len(env))
+		}
+
+		maps.Copy(values, env)
+
+		hostKeys = append(slices.Clone(hostKeys), virtualHostEnv)
+		schemeKeys = append(slices.Clone(schemeKeys), virtualProtoEnv)
+		portKeys = append(slices.Clone(portKeys), virtualPortEnv)
+		paths = append(slices.Clone(paths), virtualPathEnv)
+	}
+
+	// determine the hosts
+	for _, wantHostKey := range hostKeys {
+		if v, ok := values[wantHostKey]; ok {
+			if strings.TrimSpace(v) == "" {
+				diag.warn("host label", "%s is empty, ignored", describeKey(wantHostKey))
+
+				continue
+			}
+
+			var templated = isHostnameTemplate(v)
+
+			if templated {
+				rendered, err := renderHostnameTemplate(v, info)
+				if err != nil {
+					diag.fail("host label", "%s template: %s", describeKey(wantHostKey), err)
+
+					continue
+				}
+
+				v = rendered
+			}
+
+			for h := range strings.SplitSeq(v, ",") {
+				h = strings.ToLower(strings.TrimSpace(h))
+
+				switch {
+				case h == "":
+					continue
+				case strings.ContainsAny(h, "*~"):
+					diag.warn("host label", "wildcard and regex hostnames are not supported, %q ignored", h)
+
+					continue
+				case templated && !IsValidHostname(normalizeHostname(h)):
+					diag.warn("host label", "the rendered hostname %q is not a valid DNS name, ignored", h)
+
+					continue
+				}
+
+				h = normalizeHostname(h)
+
+				if !slices.Contains(route.hosts, h) {
+					route.hosts = append(route.hosts, h)
+				}
+			}
+
+			if len(route.hosts) > 0 {
+				diag.ok("host label", "the hostname %s is set using %s",
+					quoteJoin(route.hosts), describeKey(wantHostKey),
+				)
+
+				break
+			}
+		}
+	}
+
+	// the containers without the host label are routed using the network domain (if any)
+	if len(route.hosts) == 0 {
+		route.hosts, route.network = s.domainHosts(info, diag)
+	}
+
+	if len(route.hosts) == 0 {
+		diag.fail("host label", "no host label found (expected one of: %s)", strings.Join(hostKeys, ", "))
+	}
+
+	// the container terminates TLS itself, so the upstream speaks TLS (unless the scheme label says otherwise)
+	if route.passthrough = s.tlsPassthrough(info.Labels, diag); route.passthrough {
+		route.scheme, route.port = SchemeHTTPS, 443 //nolint:mnd
+	}
+
+	// determine the scheme
+	for _, wantSchemeKey := range schemeKeys {
+		if v, ok := values[wantSchemeKey]; ok {
+			v = strings.ToLower(strings.TrimSpace(v))
+
+			if v == "" {
+				continue
+			}
+
+			if !IsSupportedScheme(v) {
+				diag.warn("scheme label", "the %s scheme (set using %s) is not supported, http is used",
+					v, describeKey(wantSchemeKey),
+				)
+
+				break
+			}
+
+			if IsTLSScheme(v) {
+				route.port = 443 // in case of https (or grpcs), set the default port to 443
+			}
+
+			route.scheme = v
+
+			diag.ok("scheme label", "the scheme %q is set using %s", route.scheme, describeKey(wantSchemeKey))
+
+			break
+		}
+	}
+
+	var portSet bool
+
+	// determine the port
+	for _, wantPortKey := range portKeys {
+		if v, ok := values[wantPortKey]; ok {
+			v = strings.TrimSpace(v)
+
+			if v == "" {
+				diag.warn("port label", "%s is empty, ignored", describeKey(wantPortKey))
+
+				continue
+			}
+
+			portSet = true
+
+			// parse the port
+			if parsed, parseErr := strconv.ParseUint(v, 10, 16); parseErr == nil {
+				route.port = uint16(parsed)
+
+				diag.ok("port label", "the port %d is set using %s", route.port, describeKey(wantPortKey))
+			} else {
+				diag.warn("port label", "%s value %q is not a valid port number, the default port %d is used",
+					describeKey(wantPortKey), v, route.port,
+				)
+			}
+
+			break
+		}
+	}
+
+	// the network domain routes use the single exposed port (if any), since the container has no labels at all
+	if route.network != "" && !portSet {
+		if port, ok := singleExposedPort(info.Ports); ok {
+			route.port = port
+
+			diag.ok("port label", "the port %d is the only port, exposed by the container", route.port)
+		}
+	}
+
+	// determine the path prefix
+	for _, wantPathKey := range paths {
+		if v, ok := values[wantPathKey]; ok {
+			if route.pathPrefix = NormalizePathPrefix(v); route.pathPrefix != "" {
+				diag.ok("path", "only requests with the %s path prefix are routed (set using %s)",
+					route.pathPrefix, describeKey(wantPathKey),
+				)
 			}
+
+			break
+		}
+	}
+
+	return route
+}
+
+// tlsPassthrough reads the TLS passthrough label of the container.
+func (s *State) tlsPassthrough(labels map[string]string, diag *diagnostics) bool {
+	for _, wantLabel := range s.labels.tlsPassthrough {
+		if v, ok := labels[wantLabel]; ok {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				diag.warn("tls passthrough", "the %s label value %q is not a boolean, ignored", wantLabel, v)
+
+				return false
+			}
+
+			if parsed {
+				diag.ok("tls passthrough", "the raw TLS connections are passed to the container (SNI-based), "+
+					"it must terminate TLS itself")
+			}
+
+			return parsed
 		}
 	}
 
-	return "", "", "", 0, false
+	return false
+}
+
+// normalizeHostname returns the lowercased hostname without the ".indocker.app" suffix.
+func normalizeHostname(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".indocker.app")
+}
+
+// describeKey returns the human-readable description of the label (or the environment variable) name.
+func describeKey(key string) string {
+	if strings.HasPrefix(key, "VIRTUAL_") {
+		return "the " + key + " environment variable"
+	}
+
+	return "the " + key + " label"
+}
+
+// quoteJoin returns the comma-separated list of the quoted strings.
+func quoteJoin(list []string) string {
+	var quoted = make([]string, len(list))
+
+	for i, v := range list {
+		quoted[i] = strconv.Quote(v)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// NormalizePathPrefix returns the path prefix with the leading slash, and without the trailing one. The root path
+// (and empty string) is normalized to the empty string, which means "any path".
+func NormalizePathPrefix(p string) string {
+	p = strings.TrimRight(strings.TrimSpace(p), "/")
+
+	if p == "" {
+		return ""
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	return p
 }
@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_IsManagedContainer(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveOpts     []StateOption
+		giveDomains  map[string]string
+		giveLabels   map[string]string
+		giveEnv      []string
+		giveNetworks []string
+		want         bool
+	}{
+		"host label": {
+			giveLabels: map[string]string{"indocker.host": "app"},
+			want:       true,
+		},
+		"empty host label": {
+			giveLabels: map[string]string{"indocker.host": " "},
+		},
+		"no labels": {},
+		"traefik labels": {
+			giveOpts:   []StateOption{WithTraefikLabels(true)},
+			giveLabels: map[string]string{"traefik.http.routers.app.rule": "Host(`app`)"},
+			want:       true,
+		},
+		"traefik labels (disabled)": {
+			giveLabels: map[string]string{"traefik.http.routers.app.rule": "Host(`app`)"},
+		},
+		"virtual host env": {
+			giveOpts: []StateOption{WithVirtualHostEnv(true)},
+			giveEnv:  []string{"PATH=/bin", "VIRTUAL_HOST=app"},
+			want:     true,
+		},
+		"virtual host env (disabled)": {
+			giveEnv: []string{"VIRTUAL_HOST=app"},
+		},
+		"network domain": {
+			giveDomains:  map[string]string{"feature-x": "feature-x"},
+			giveNetworks: []string{"bridge", "feature-x"},
+			want:         true,
+		},
+		"network without domain": {
+			giveDomains:  map[string]string{"feature-x": "feature-x"},
+			giveNetworks: []string{"bridge"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				state = NewState(nil, tt.giveOpts...)
+				info  = container.InspectResponse{
+					Config:          &container.Config{Labels: tt.giveLabels, Env: tt.giveEnv},
+					NetworkSettings: &container.NetworkSettings{Networks: make(map[string]*network.EndpointSettings)},
+				}
+			)
+
+			state.domains = tt.giveDomains
+
+			for _, name := range tt.giveNetworks {
+				info.NetworkSettings.Networks[name] = &network.EndpointSettings{}
+			}
+
+			assert.Equal(t, tt.want, state.IsManagedContainer(info))
+		})
+	}
+}
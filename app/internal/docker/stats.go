@@ -0,0 +1,152 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+type (
+	StatsReader interface {
+		ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error)
+	}
+
+	// ResourceUsage contains the resource usage figures of the container (or a group of containers).
+	ResourceUsage struct {
+		CPUPercent  float64 // CPU usage in percents (100% = one CPU core is fully used)
+		MemoryUsage uint64  // memory usage in bytes (without the page cache)
+		MemoryLimit uint64  // memory limit in bytes
+		NetworkRx   uint64  // received bytes over all container networks
+		NetworkTx   uint64  // sent bytes over all container networks
+		BlockRead   uint64  // read bytes from the block devices
+		BlockWrite  uint64  // written bytes to the block devices
+	}
+)
+
+// MemoryPercent returns the memory usage in percents of the memory limit.
+func (u ResourceUsage) MemoryPercent() float64 {
+	if u.MemoryLimit == 0 {
+		return 0
+	}
+
+	return float64(u.MemoryUsage) / float64(u.MemoryLimit) * 100 //nolint:mnd
+}
+
+// Add returns the sum of the resource usages (useful for aggregating the usage of the container replicas).
+func (u ResourceUsage) Add(other ResourceUsage) ResourceUsage {
+	return ResourceUsage{
+		CPUPercent:  u.CPUPercent + other.CPUPercent,
+		MemoryUsage: u.MemoryUsage + other.MemoryUsage,
+		MemoryLimit: u.MemoryLimit + other.MemoryLimit,
+		NetworkRx:   u.NetworkRx + other.NetworkRx,
+		NetworkTx:   u.NetworkTx + other.NetworkTx,
+		BlockRead:   u.BlockRead + other.BlockRead,
+		BlockWrite:  u.BlockWrite + other.BlockWrite,
+	}
+}
+
+// UsageFromStats calculates the resource usage using the stats, returned by the docker API. The calculation is
+// the same as the `docker stats` command does.
+func UsageFromStats(s container.StatsResponse) (u ResourceUsage) {
+	// cpu usage
+	var (
+		cpuDelta    = float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+		systemDelta = float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+		onlineCPUs  = float64(s.CPUStats.OnlineCPUs)
+	)
+
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if systemDelta > 0 && cpuDelta > 0 {
+		u.CPUPercent = cpuDelta / systemDelta * onlineCPUs * 100 //nolint:mnd
+	}
+
+	// memory usage (the page cache is excluded, as the docker CLI does)
+	u.MemoryUsage, u.MemoryLimit = s.MemoryStats.Usage, s.MemoryStats.Limit
+
+	for _, cacheKey := range []string{"total_inactive_file", "inactive_file"} { // cgroup v1, cgroup v2
+		if v, ok := s.MemoryStats.Stats[cacheKey]; ok && v < u.MemoryUsage {
+			u.MemoryUsage -= v
+
+			break
+		}
+	}
+
+	// network usage
+	for _, n := range s.Networks {
+		u.NetworkRx += n.RxBytes
+		u.NetworkTx += n.TxBytes
+	}
+
+	// block IO usage
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			u.BlockRead += e.Value
+		case "write":
+			u.BlockWrite += e.Value
+		}
+	}
+
+	return
+}
+
+// ContainerUsage returns the current resource usage of the container. Note that this call may take a while (up to
+// a few seconds), since docker needs to collect two samples to calculate the CPU usage.
+func ContainerUsage(ctx context.Context, r StatsReader, containerID string) (ResourceUsage, error) {
+	resp, err := r.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return ResourceUsage{}, fmt.Errorf("failed to get the container stats: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var stats container.StatsResponse
+
+	if err = json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return ResourceUsage{}, fmt.Errorf("failed to decode the container stats: %w", err)
+	}
+
+	return UsageFromStats(stats), nil
+}
+
+// StreamContainerUsage streams the resource usage of the container to the given function (every second, as the
+// docker API does). It blocks until the context is canceled, the stream is closed, or the function returns an error.
+func StreamContainerUsage(
+	ctx context.Context,
+	r StatsReader,
+	containerID string,
+	fn func(ResourceUsage) error,
+) error {
+	resp, err := r.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return fmt.Errorf("failed to get the container stats: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var decoder = json.NewDecoder(resp.Body)
+
+	for {
+		var stats container.StatsResponse
+
+		if err = decoder.Decode(&stats); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("failed to decode the container stats: %w", err)
+		}
+
+		if err = fn(UsageFromStats(stats)); err != nil {
+			return err
+		}
+	}
+}
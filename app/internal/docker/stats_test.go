@@ -0,0 +1,96 @@
+package docker_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+func TestUsageFromStats(t *testing.T) {
+	t.Parallel()
+
+	var usage = docker.UsageFromStats(container.StatsResponse{
+		CPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 2_000},
+			SystemUsage: 20_000,
+			OnlineCPUs:  4,
+		},
+		PreCPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 1_000},
+			SystemUsage: 10_000,
+		},
+		MemoryStats: container.MemoryStats{
+			Usage: 300,
+			Limit: 1_000,
+			Stats: map[string]uint64{"inactive_file": 100},
+		},
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 10, TxBytes: 20},
+			"eth1": {RxBytes: 1, TxBytes: 2},
+		},
+		BlkioStats: container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+			{Op: "Read", Value: 5},
+			{Op: "write", Value: 7},
+			{Op: "read", Value: 1},
+			{Op: "Total", Value: 13},
+		}},
+	})
+
+	assert.InDelta(t, 40.0, usage.CPUPercent, 0.0001)
+	assert.EqualValues(t, 200, usage.MemoryUsage)
+	assert.EqualValues(t, 1_000, usage.MemoryLimit)
+	assert.InDelta(t, 20.0, usage.MemoryPercent(), 0.0001)
+	assert.EqualValues(t, 11, usage.NetworkRx)
+	assert.EqualValues(t, 22, usage.NetworkTx)
+	assert.EqualValues(t, 6, usage.BlockRead)
+	assert.EqualValues(t, 7, usage.BlockWrite)
+
+	var sum = usage.Add(usage)
+
+	assert.InDelta(t, 80.0, sum.CPUPercent, 0.0001)
+	assert.EqualValues(t, 400, sum.MemoryUsage)
+	assert.InDelta(t, 20.0, sum.MemoryPercent(), 0.0001)
+}
+
+type statsReaderFunc func(stream bool) (container.StatsResponseReader, error)
+
+func (f statsReaderFunc) ContainerStats(_ context.Context, _ string, stream bool) (container.StatsResponseReader, error) {
+	return f(stream)
+}
+
+func TestStreamContainerUsage(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	for i := range 3 {
+		require.NoError(t, json.NewEncoder(&buf).Encode(container.StatsResponse{
+			MemoryStats: container.MemoryStats{Usage: uint64(i + 1), Limit: 10},
+		}))
+	}
+
+	var (
+		reader = statsReaderFunc(func(stream bool) (container.StatsResponseReader, error) {
+			assert.True(t, stream)
+
+			return container.StatsResponseReader{Body: io.NopCloser(&buf)}, nil
+		})
+		got []uint64
+	)
+
+	require.NoError(t, docker.StreamContainerUsage(context.Background(), reader, "foo", func(u docker.ResourceUsage) error {
+		got = append(got, u.MemoryUsage)
+
+		return nil
+	}))
+
+	assert.Equal(t, []uint64{1, 2, 3}, got)
+}
@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"strconv"
+	"strings"
+)
+
+// upstreamTLS reads the upstream TLS settings labels of the container. The settings are used only for the TLS
+// schemes (https, grpcs), so they are ignored (and reported) for the rest.
+func (s *State) upstreamTLS(labels map[string]string, scheme string, diag *diagnostics) UpstreamTLS {
+	var (
+		t UpstreamTLS
+
+		// value returns the first non-empty label value, and the label name
+		value = func(names []string) (string, string, bool) {
+			for _, name := range names {
+				if v := strings.TrimSpace(labels[name]); v != "" {
+					return v, name, true
+				}
+			}
+
+			return "", "", false
+		}
+	)
+
+	t.CAFile, _, _ = value(s.labels.tlsCA)
+	t.ServerName, _, _ = value(s.labels.tlsServerName)
+	t.Verify = t.CAFile != "" // the CA bundle implies the verification, unless it's disabled explicitly
+
+	if v, name, ok := value(s.labels.tlsVerify); ok {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			t.Verify = parsed
+		} else {
+			diag.warn("upstream tls", "the %s label value %q is not a boolean, ignored", name, v)
+		}
+	}
+
+	var (
+		cert, certName, hasCert = value(s.labels.tlsCert)
+		key, keyName, hasKey    = value(s.labels.tlsKey)
+	)
+
+	switch {
+	case hasCert && hasKey:
+		t.CertFile, t.KeyFile = cert, key
+	case hasCert:
+		diag.warn("upstream tls", "the %s label is set without the key (%s), the client certificate is not used",
+			certName, strings.Join(s.labels.tlsKey, " or "),
+		)
+	case hasKey:
+		diag.warn("upstream tls", "the %s label is set without the certificate (%s), the client certificate is "+
+			"not used", keyName, strings.Join(s.labels.tlsCert, " or "),
+		)
+	}
+
+	if t.IsZero() {
+		return t
+	}
+
+	if !IsTLSScheme(scheme) {
+		diag.warn("upstream tls", "the upstream TLS settings are ignored, since the %q scheme does not use TLS", scheme)
+
+		return UpstreamTLS{}
+	}
+
+	switch {
+	case t.Verify && t.CAFile != "":
+		diag.ok("upstream tls", "the upstream certificate is verified using the %s CA bundle", t.CAFile)
+	case t.Verify:
+		diag.ok("upstream tls", "the upstream certificate is verified using the system CA bundle")
+	default:
+		diag.info("upstream tls", "the upstream certificate is not verified")
+	}
+
+	switch {
+	case t.ServerName != "":
+		diag.ok("upstream tls", "the %q server name (SNI) is sent to the upstream", t.ServerName)
+	case t.Verify:
+		diag.info("upstream tls", "the upstream certificate is verified against the container IP address, set the "+
+			"%s label if it's issued for a hostname", strings.Join(s.labels.tlsServerName, " or "),
+		)
+	}
+
+	if t.CertFile != "" {
+		diag.ok("upstream tls", "the %s client certificate is presented to the upstream", t.CertFile)
+	}
+
+	return t
+}
@@ -0,0 +1,406 @@
+package docker
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// the Traefik (v2 and v3) labels, supported in the Traefik compatibility mode.
+const (
+	traefikLabelsPrefix     = "traefik."
+	traefikEnableLabel      = "traefik.enable"
+	traefikNetworkLabel     = "traefik.docker.network"
+	traefikRoutersPrefix    = "traefik.http.routers."
+	traefikServicesPrefix   = "traefik.http.services."
+	traefikMiddlewarePrefix = "traefik.http.middlewares."
+)
+
+type (
+	// traefikRouter is the Traefik HTTP router, defined using the labels.
+	traefikRouter struct {
+		name, rule, service string
+		middlewares         []string
+	}
+
+	// traefikService is the Traefik HTTP service (the load balancer server), defined using the labels.
+	traefikService struct {
+		port, scheme string
+		passHost     *bool
+	}
+
+	// traefikMiddleware is the Traefik HTTP middleware (only stripprefix and headers are supported).
+	traefikMiddleware struct {
+		stripPrefixes []string
+		headers       map[string]string
+	}
+
+	// traefikLabels is the parsed Traefik configuration of the container.
+	traefikLabels struct {
+		routers     map[string]*traefikRouter
+		services    map[string]*traefikService
+		middlewares map[string]*traefikMiddleware
+		unsupported []string // the label names, which are not supported (ignored)
+	}
+)
+
+// isTraefikContainer returns true if the container labels contain at least one Traefik HTTP router rule.
+func isTraefikContainer(labels map[string]string) bool {
+	for k := range labels {
+		if strings.HasPrefix(k, traefikRoutersPrefix) && strings.EqualFold(k[strings.LastIndex(k, ".")+1:], "rule") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTraefikLabels parses the Traefik labels of the container.
+func parseTraefikLabels(labels map[string]string) traefikLabels { //nolint:funlen,gocognit,gocyclo
+	var t = traefikLabels{
+		routers:     make(map[string]*traefikRouter),
+		services:    make(map[string]*traefikService),
+		middlewares: make(map[string]*traefikMiddleware),
+	}
+
+	var (
+		router = func(name string) *traefikRouter {
+			if _, ok := t.routers[name]; !ok {
+				t.routers[name] = &traefikRouter{name: name}
+			}
+
+			return t.routers[name]
+		}
+		service = func(name string) *traefikService {
+			if _, ok := t.services[name]; !ok {
+				t.services[name] = &traefikService{}
+			}
+
+			return t.services[name]
+		}
+		middleware = func(name string) *traefikMiddleware {
+			if _, ok := t.middlewares[name]; !ok {
+				t.middlewares[name] = &traefikMiddleware{}
+			}
+
+			return t.middlewares[name]
+		}
+	)
+
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		var value = strings.TrimSpace(labels[key])
+
+		switch {
+		case !strings.HasPrefix(key, traefikLabelsPrefix), key == traefikEnableLabel, key == traefikNetworkLabel:
+			continue // not a Traefik label, or handled separately
+
+		case strings.HasPrefix(key, traefikRoutersPrefix):
+			var name, attr, _ = strings.Cut(strings.TrimPrefix(key, traefikRoutersPrefix), ".")
+
+			switch attr = strings.ToLower(attr); {
+			case attr == "rule":
+				router(name).rule = value
+			case attr == "service":
+				router(name).service = value
+			case attr == "middlewares":
+				for m := range strings.SplitSeq(value, ",") {
+					if m = strings.TrimSpace(m); m != "" {
+						router(name).middlewares = append(router(name).middlewares, m)
+					}
+				}
+			case attr == "entrypoints", attr == "priority", attr == "tls", strings.HasPrefix(attr, "tls."):
+				// not needed - indocker serves every route using both HTTP and HTTPS
+			default:
+				t.unsupported = append(t.unsupported, key)
+			}
+
+		case strings.HasPrefix(key, traefikServicesPrefix):
+			var name, attr, _ = strings.Cut(strings.TrimPrefix(key, traefikServicesPrefix), ".")
+
+			switch strings.ToLower(attr) {
+			case "loadbalancer.server.port":
+				service(name).port = value
+			case "loadbalancer.server.scheme":
+				service(name).scheme = strings.ToLower(value)
+			case "loadbalancer.passhostheader":
+				if v, err := strconv.ParseBool(value); err == nil {
+					service(name).passHost = &v
+				} else {
+					t.unsupported = append(t.unsupported, key)
+				}
+			default:
+				t.unsupported = append(t.unsupported, key)
+			}
+
+		case strings.HasPrefix(key, traefikMiddlewarePrefix):
+			var name, attr, _ = strings.Cut(strings.TrimPrefix(key, traefikMiddlewarePrefix), ".")
+
+			// the header names are case-sensitive (at least, for the upstream), so only the prefix is lowercased
+			const customHeadersPrefix = "headers.customrequestheaders."
+
+			switch lower := strings.ToLower(attr); {
+			case lower == "stripprefix.prefixes":
+				for p := range strings.SplitSeq(value, ",") {
+					if p = NormalizePathPrefix(p); p != "" {
+						middleware(name).stripPrefixes = append(middleware(name).stripPrefixes, p)
+					}
+				}
+			case strings.HasPrefix(lower, customHeadersPrefix) && len(attr) > len(customHeadersPrefix):
+				var m = middleware(name)
+
+				if m.headers == nil {
+					m.headers = make(map[string]string)
+				}
+
+				m.headers[attr[len(customHeadersPrefix):]] = value
+			default:
+				t.unsupported = append(t.unsupported, key)
+			}
+
+		default: // e.g. TCP/UDP routers
+			t.unsupported = append(t.unsupported, key)
+		}
+	}
+
+	return t
+}
+
+var (
+	// traefikRuleMatcher matches the supported Traefik rule matchers - Host and PathPrefix.
+	traefikRuleMatcher = regexp.MustCompile(`(Host|PathPrefix)\(([^)]*)\)`) //nolint:gochecknoglobals
+	// traefikRuleOperators matches everything, allowed in the rule besides the supported matchers.
+	traefikRuleOperators = regexp.MustCompile(`&&|\|\||[()\s]`) //nolint:gochecknoglobals
+)
+
+// parseTraefikRule extracts the hostnames and the path prefix from the Traefik router rule (e.g.
+// "Host(`app.indocker.app`) && PathPrefix(`/api`)"). The unsupported part of the rule (if any) is returned too.
+func parseTraefikRule(rule string) (hosts []string, pathPrefix, unsupported string) {
+	for _, match := range traefikRuleMatcher.FindAllStringSubmatch(rule, -1) {
+		for arg := range strings.SplitSeq(match[2], ",") {
+			if arg = strings.Trim(strings.TrimSpace(arg), "`\"'"); arg == "" {
+				continue
+			}
+
+			switch match[1] {
+			case "Host":
+				if h := normalizeHostname(arg); !slices.Contains(hosts, h) {
+					hosts = append(hosts, h)
+				}
+			case "PathPrefix":
+				if pathPrefix == "" {
+					pathPrefix = NormalizePathPrefix(arg)
+				}
+			}
+		}
+	}
+
+	unsupported = strings.TrimSpace(traefikRuleMatcher.ReplaceAllString(rule, ""))
+
+	if traefikRuleOperators.ReplaceAllString(unsupported, "") == "" {
+		unsupported = "" // only the operators and the parentheses are left
+	}
+
+	return hosts, pathPrefix, unsupported
+}
+
+// traefikRoute determines the hostnames, scheme, port, path prefix and the options of the route to the container
+// using the common subset of the Traefik labels:
+//
+//   - traefik.enable=false disables the routing
+//   - traefik.http.routers.<name>.rule (only the Host and PathPrefix matchers are supported)
+//   - traefik.http.routers.<name>.service and traefik.http.routers.<name>.middlewares
+//   - traefik.http.services.<name>.loadbalancer.server.port (or the single exposed port, as Traefik does)
+//   - traefik.http.services.<name>.loadbalancer.server.scheme (http or https)
+//   - traefik.http.services.<name>.loadbalancer.passhostheader (the original Host header is passed by default)
+//   - traefik.http.middlewares.<name>.stripprefix.prefixes
+//   - traefik.http.middlewares.<name>.headers.customrequestheaders.<header>
+//
+// The routers with the same path prefix, service and middlewares are merged (e.g. the "http" and "https" routers
+// with the same rule), and the other ones are ignored. Every unsupported label is reported to the diagnostics.
+func traefikRoute(info container.Summary, diag *diagnostics) (route containerRoute) { //nolint:funlen,gocognit,gocyclo
+	route.scheme, route.port, route.preserveHost = "http", uint16(80), true //nolint:mnd // defaults
+
+	if enabled, err := strconv.ParseBool(strings.TrimSpace(info.Labels[traefikEnableLabel])); err == nil && !enabled {
+		diag.fail("host label", "the routing is disabled using the %s label", traefikEnableLabel)
+
+		return route
+	}
+
+	var t = parseTraefikLabels(info.Labels)
+
+	if len(t.unsupported) > 0 {
+		diag.warn("traefik labels", "the following labels are not supported and ignored: %s",
+			strings.Join(t.unsupported, ", "),
+		)
+	}
+
+	var primary *traefikRouter
+
+	// determine the hosts
+	for _, name := range slices.Sorted(maps.Keys(t.routers)) {
+		var (
+			r                                = t.routers[name]
+			ruleLabel                        = traefikRoutersPrefix + name + ".rule"
+			hosts, pathPrefix, unsupportedIn = parseTraefikRule(r.rule)
+		)
+
+		if unsupportedIn != "" {
+			diag.warn("traefik router", "the %q part of the %s label is not supported, ignored", unsupportedIn, ruleLabel)
+		}
+
+		if len(hosts) == 0 {
+			diag.warn("traefik router", "the %q router has no Host rule, ignored", name)
+
+			continue
+		}
+
+		if primary == nil {
+			primary, route.hosts, route.pathPrefix = r, hosts, pathPrefix
+
+			diag.ok("host label", "the hostname %s is set using %s", quoteJoin(hosts), describeKey(ruleLabel))
+
+			continue
+		}
+
+		if pathPrefix != route.pathPrefix || r.service != primary.service ||
+			!slices.Equal(r.middlewares, primary.middlewares) {
+			diag.warn("traefik router", "the %q router differs from the %q one (by the path prefix, service or "+
+				"middlewares), only one route per container is supported - ignored", name, primary.name)
+
+			continue
+		}
+
+		for _, h := range hosts {
+			if !slices.Contains(route.hosts, h) {
+				route.hosts = append(route.hosts, h)
+			}
+		}
+
+		diag.ok("host label", "the hostname %s is set using %s", quoteJoin(hosts), describeKey(ruleLabel))
+	}
+
+	if primary == nil {
+		diag.fail("host label", "no Traefik router with the Host rule found")
+
+		return route
+	}
+
+	if route.pathPrefix != "" {
+		diag.ok("path", "only requests with the %s path prefix are routed (set using the %s label)",
+			route.pathPrefix, traefikRoutersPrefix+primary.name+".rule",
+		)
+	}
+
+	// determine the service
+	var serviceName = primary.service
+
+	if serviceName == "" && len(t.services) == 1 { // Traefik links the single service to the routers automatically
+		for serviceName = range t.services {
+			break
+		}
+	}
+
+	var svc, svcFound = t.services[serviceName]
+
+	if serviceName != "" && !svcFound {
+		diag.warn("traefik service", "the %q service is not defined using the container labels, ignored", serviceName)
+	}
+
+	if svcFound {
+		var svcLabel = traefikServicesPrefix + serviceName + ".loadbalancer"
+
+		switch svc.scheme {
+		case "":
+		case SchemeHTTP, SchemeHTTPS, SchemeH2C:
+			route.scheme = svc.scheme
+
+			if route.scheme == SchemeHTTPS {
+				route.port = 443 // in case of https, set the default port to 443
+			}
+
+			diag.ok("scheme label", "the scheme %q is set using the %s.server.scheme label", route.scheme, svcLabel)
+		default:
+			diag.warn("scheme label", "the %q scheme (set using the %s.server.scheme label) is not supported, "+
+				"http is used", svc.scheme, svcLabel)
+		}
+
+		if svc.port != "" {
+			if parsed, parseErr := strconv.ParseUint(svc.port, 10, 16); parseErr == nil {
+				route.port = uint16(parsed)
+
+				diag.ok("port label", "the port %d is set using the %s.server.port label", route.port, svcLabel)
+			} else {
+				diag.warn("port label", "the %s.server.port label value %q is not a valid port number, the default "+
+					"port %d is used", svcLabel, svc.port, route.port)
+			}
+		}
+
+		if svc.passHost != nil {
+			route.preserveHost = *svc.passHost
+		}
+	}
+
+	if !svcFound || svc.port == "" {
+		// Traefik uses the single exposed port, if the port is not set explicitly
+		if port, ok := singleExposedPort(info.Ports); ok {
+			route.port = port
+
+			diag.ok("port label", "the port %d is the only port, exposed by the container", route.port)
+		}
+	}
+
+	// apply the middlewares
+	for _, ref := range primary.middlewares {
+		var name, provider, _ = strings.Cut(ref, "@")
+
+		if provider != "" && provider != "docker" {
+			diag.warn("traefik middleware", "the %q middleware is defined outside the container labels, ignored", ref)
+
+			continue
+		}
+
+		m, ok := t.middlewares[name]
+		if !ok {
+			diag.warn("traefik middleware", "the %q middleware is not supported or not defined, ignored", ref)
+
+			continue
+		}
+
+		route.stripPrefixes = append(route.stripPrefixes, m.stripPrefixes...)
+
+		if len(m.headers) > 0 {
+			if route.headers == nil {
+				route.headers = make(map[string]string, len(m.headers))
+			}
+
+			maps.Copy(route.headers, m.headers)
+		}
+
+		diag.ok("traefik middleware", "the %q middleware is applied", name)
+	}
+
+	return route
+}
+
+// singleExposedPort returns the private TCP port, if the container exposes exactly one.
+func singleExposedPort(ports []container.Port) (uint16, bool) {
+	var found uint16
+
+	for _, p := range ports {
+		if p.Type != "" && p.Type != "tcp" {
+			continue
+		}
+
+		if found != 0 && found != p.PrivatePort {
+			return 0, false // multiple ports
+		}
+
+		found = p.PrivatePort
+	}
+
+	return found, found != 0
+}
@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraefikRule(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveRule        string
+		wantHosts       []string
+		wantPathPrefix  string
+		wantUnsupported string
+	}{
+		"host": {
+			giveRule:  "Host(`App.indocker.app`)",
+			wantHosts: []string{"app"},
+		},
+		"multiple hosts (v2)": {
+			giveRule:  "Host(`foo.indocker.app`, `bar.indocker.app`)",
+			wantHosts: []string{"foo", "bar"},
+		},
+		"host or host": {
+			giveRule:  "Host(`foo`) || Host(`bar`) || Host(`foo`)",
+			wantHosts: []string{"foo", "bar"},
+		},
+		"host and path prefix": {
+			giveRule:       "(Host(\"app.indocker.app\") && PathPrefix(`/api/`))",
+			wantHosts:      []string{"app"},
+			wantPathPrefix: "/api",
+		},
+		"unsupported matcher": {
+			giveRule:        "Host(`app`) && Method(`GET`)",
+			wantHosts:       []string{"app"},
+			wantUnsupported: "&& Method(`GET`)",
+		},
+		"regexp only": {
+			giveRule:        "HostRegexp(`{sub:[a-z]+}.example.com`)",
+			wantUnsupported: "HostRegexp(`{sub:[a-z]+}.example.com`)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			hosts, pathPrefix, unsupported := parseTraefikRule(tt.giveRule)
+
+			assert.Equal(t, tt.wantHosts, hosts)
+			assert.Equal(t, tt.wantPathPrefix, pathPrefix)
+			assert.Equal(t, tt.wantUnsupported, unsupported)
+		})
+	}
+}
+
+func TestState_buildRouteToContainerTraefik(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveLabels map[string]string
+		givePorts  []container.Port
+		wantRoute  containerRoute
+		wantFound  bool
+		wantLevels map[string]DiagnosticLevel
+	}{
+		"full": {
+			giveLabels: map[string]string{
+				"traefik.enable":                              "true",
+				"traefik.http.routers.web.rule":               "Host(`app.indocker.app`) && PathPrefix(`/api`)",
+				"traefik.http.routers.web.entrypoints":        "web",
+				"traefik.http.routers.web.middlewares":        "strip@docker, hdr",
+				"traefik.http.routers.web.service":            "app",
+				"traefik.http.routers.web-secure.rule":        "Host(`www.indocker.app`) && PathPrefix(`/api`)",
+				"traefik.http.routers.web-secure.tls":         "true",
+				"traefik.http.routers.web-secure.middlewares": "strip@docker,hdr",
+				"traefik.http.routers.web-secure.service":     "app",
+
+				"traefik.http.services.app.loadbalancer.server.port":    "8443",
+				"traefik.http.services.app.loadbalancer.server.scheme":  "https",
+				"traefik.http.services.app.loadbalancer.passhostheader": "false",
+
+				"traefik.http.middlewares.strip.stripprefix.prefixes":             "/api",
+				"traefik.http.middlewares.hdr.headers.customrequestheaders.X-Foo": "bar",
+			},
+			wantRoute: containerRoute{
+				scheme:        "https",
+				hosts:         []string{"app", "www"},
+				ipAddr:        "10.0.0.1",
+				port:          8443,
+				pathPrefix:    "/api",
+				headers:       map[string]string{"X-Foo": "bar"},
+				stripPrefixes: []string{"/api"},
+			},
+			wantFound: true,
+			wantLevels: map[string]DiagnosticLevel{
+				"host label":         DiagnosticOK,
+				"port label":         DiagnosticOK,
+				"scheme label":       DiagnosticOK,
+				"traefik middleware": DiagnosticOK,
+			},
+		},
+		"single exposed port": {
+			giveLabels: map[string]string{"traefik.http.routers.app.rule": "Host(`app`)"},
+			givePorts:  []container.Port{{PrivatePort: 3000, Type: "tcp"}, {PrivatePort: 3000, PublicPort: 3000}},
+			wantRoute: containerRoute{
+				scheme: "http", hosts: []string{"app"}, ipAddr: "10.0.0.1", port: 3000, preserveHost: true,
+			},
+			wantFound: true,
+		},
+		"unsupported labels": {
+			giveLabels: map[string]string{
+				"traefik.http.routers.app.rule":                        "Host(`app`)",
+				"traefik.http.routers.app.middlewares":                 "auth,other@file",
+				"traefik.http.middlewares.auth.basicauth.users":        "foo:bar",
+				"traefik.tcp.routers.db.rule":                          "HostSNI(`*`)",
+				"traefik.http.services.app.loadbalancer.server.scheme": "ftp",
+			},
+			wantRoute: containerRoute{
+				scheme: "http", hosts: []string{"app"}, ipAddr: "10.0.0.1", port: 80, preserveHost: true,
+			},
+			wantFound: true,
+			wantLevels: map[string]DiagnosticLevel{
+				"traefik labels":     DiagnosticWarn,
+				"traefik middleware": DiagnosticWarn,
+				"scheme label":       DiagnosticWarn,
+			},
+		},
+		"disabled": {
+			giveLabels: map[string]string{"traefik.enable": "false", "traefik.http.routers.app.rule": "Host(`app`)"},
+			wantLevels: map[string]DiagnosticLevel{"host label": DiagnosticError},
+		},
+		"indocker labels take precedence": {
+			giveLabels: map[string]string{"indocker.host": "foo", "traefik.http.routers.app.rule": "Host(`app`)"},
+			wantRoute:  containerRoute{scheme: "http", hosts: []string{"foo"}, ipAddr: "10.0.0.1", port: 80},
+			wantFound:  true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var diag = new(diagnostics)
+
+			route, found := NewState(nil, WithTraefikLabels(true)).buildRouteToContainer(container.Summary{
+				State:           container.StateRunning,
+				Labels:          tt.giveLabels,
+				Ports:           tt.givePorts,
+				NetworkSettings: withNetworks("bridge"),
+			}, nil, diag)
+
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantRoute, route)
+
+			var levels = make(map[string]DiagnosticLevel, len(diag.steps))
+
+			for _, step := range diag.steps {
+				levels[step.Name] = step.Level
+			}
+
+			for stepName, wantLevel := range tt.wantLevels {
+				assert.Equal(t, wantLevel, levels[stepName], stepName)
+			}
+		})
+	}
+}
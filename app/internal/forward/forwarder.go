@@ -0,0 +1,163 @@
+// Package forward implements the raw TCP and UDP port forwarding from the host to the containers, set using the
+// labels like "indocker.tcp.5432=listen:15432". The listeners are opened and closed dynamically, following the
+// containers, and the connections are forwarded to the current container address.
+package forward
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type (
+	source interface {
+		docker.PortForwardsResolver
+		docker.RoutingUpdateSubscriber
+	}
+
+	// Forwarder opens the listeners for the port forwardings, provided by the source (docker state), and forwards
+	// the connections (or datagrams) to the containers.
+	Forwarder struct {
+		log     *zap.Logger
+		source  source
+		address string // the address to listen on (e.g. "0.0.0.0")
+
+		active map[key]*active // the opened listeners (accessed only by the Run method)
+	}
+
+	// key identifies the listener.
+	key struct {
+		proto string
+		port  uint16
+	}
+
+	// active is the opened listener.
+	active struct {
+		listener    listener
+		containerID string
+		target      string
+	}
+
+	// listener is the TCP or UDP forwarding listener.
+	listener interface {
+		// SetTarget changes the upstream address (e.g. the container got another IP address).
+		SetTarget(addr string)
+
+		// Close stops the listener, and closes all the forwarded connections.
+		Close() error
+	}
+)
+
+// New creates a new forwarder. The source must be updated by the caller (e.g. using the
+// [docker.State.StartAutoUpdate]).
+func New(log *zap.Logger, source source, address string) *Forwarder {
+	return &Forwarder{log: log, source: source, address: address, active: make(map[key]*active)}
+}
+
+// Run opens (and closes) the listeners, following the port forwardings of the source. It blocks until the context
+// is canceled, and closes all the listeners before returning.
+func (f *Forwarder) Run(ctx context.Context) error {
+	var sub, stop = f.source.SubscribeForRoutingUpdates()
+	defer stop()
+
+	defer f.closeAll()
+
+	f.sync(f.source.PortForwards())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, isOpened := <-sub:
+			if !isOpened {
+				return nil
+			}
+
+			f.sync(f.source.PortForwards())
+		}
+	}
+}
+
+// sync opens the listeners for the new port forwardings, closes the listeners of the removed ones, and updates the
+// targets of the existing ones.
+func (f *Forwarder) sync(forwards []docker.PortForward) {
+	var want = make(map[key]docker.PortForward, len(forwards))
+
+	for _, fw := range forwards {
+		want[key{proto: fw.Protocol, port: fw.ListenPort}] = fw
+	}
+
+	for k, a := range f.active {
+		if _, ok := want[k]; !ok {
+			_ = a.listener.Close()
+
+			delete(f.active, k)
+
+			f.log.Info("Port forwarding stopped", zap.String("protocol", k.proto), zap.Uint16("port", k.port))
+		}
+	}
+
+	for k, fw := range want {
+		if a, ok := f.active[k]; ok {
+			if a.target != fw.Target || a.containerID != fw.ContainerID {
+				a.listener.SetTarget(fw.Target)
+				a.target, a.containerID = fw.Target, fw.ContainerID
+
+				f.log.Info("Port forwarding target changed",
+					zap.String("protocol", k.proto),
+					zap.Uint16("port", k.port),
+					zap.String("target", fw.Target),
+				)
+			}
+
+			continue
+		}
+
+		var (
+			addr = net.JoinHostPort(f.address, strconv.Itoa(int(k.port)))
+			ln   listener
+			err  error
+		)
+
+		switch k.proto {
+		case docker.ForwardTCP:
+			ln, err = listenTCP(f.log, addr, fw.Target)
+		case docker.ForwardUDP:
+			ln, err = listenUDP(f.log, addr, fw.Target)
+		default:
+			continue
+		}
+
+		if err != nil {
+			f.log.Error("Failed to start the port forwarding",
+				zap.String("protocol", k.proto),
+				zap.String("address", addr),
+				zap.String("container", fw.ContainerID),
+				zap.Error(err),
+			)
+
+			continue // will be retried on the next update
+		}
+
+		f.active[k] = &active{listener: ln, containerID: fw.ContainerID, target: fw.Target}
+
+		f.log.Info("Port forwarding started",
+			zap.String("protocol", k.proto),
+			zap.String("address", addr),
+			zap.String("target", fw.Target),
+		)
+	}
+}
+
+// closeAll closes all the listeners.
+func (f *Forwarder) closeAll() {
+	for k, a := range f.active {
+		_ = a.listener.Close()
+
+		delete(f.active, k)
+	}
+}
@@ -0,0 +1,207 @@
+package forward_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/forward"
+)
+
+// fakeSource is the port forwardings source, which notifies the subscriber on every change.
+type fakeSource struct {
+	mu       sync.Mutex
+	forwards []docker.PortForward
+	sub      chan docker.RoutesMap
+}
+
+func newFakeSource() *fakeSource { return &fakeSource{sub: make(chan docker.RoutesMap, 1)} }
+
+func (s *fakeSource) PortForwards() []docker.PortForward {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.forwards
+}
+
+func (s *fakeSource) SubscribeForRoutingUpdates() (<-chan docker.RoutesMap, func()) {
+	return s.sub, func() {}
+}
+
+func (s *fakeSource) set(forwards ...docker.PortForward) {
+	s.mu.Lock()
+	s.forwards = forwards
+	s.mu.Unlock()
+
+	s.sub <- docker.RoutesMap{}
+}
+
+// freePort returns the port, which is free (most likely) for both TCP and UDP.
+func freePort(t *testing.T) uint16 {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	require.NoError(t, ln.Close())
+
+	return uint16(ln.Addr().(*net.TCPAddr).Port) //nolint:gosec
+}
+
+// tcpServer starts the TCP server, which sends the greeting and closes the connection.
+func tcpServer(t *testing.T, greeting string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, acceptErr := ln.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			_, _ = io.WriteString(conn, greeting)
+			_ = conn.Close()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// udpServer starts the UDP server, which replies with the prefix and the datagram.
+func udpServer(t *testing.T, prefix string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		var buf = make([]byte, 1024)
+
+		for {
+			n, addr, readErr := conn.ReadFrom(buf)
+			if readErr != nil {
+				return
+			}
+
+			_, _ = conn.WriteTo(append([]byte(prefix), buf[:n]...), addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestForwarder_TCP(t *testing.T) {
+	t.Parallel()
+
+	var (
+		port   = freePort(t)
+		addr   = net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port)))
+		source = newFakeSource()
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() { _ = forward.New(zap.NewNop(), source, "127.0.0.1").Run(ctx) }()
+
+	var read = func(c *assert.CollectT) string {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		require.NoError(c, err)
+
+		defer func() { _ = conn.Close() }()
+
+		_ = conn.SetDeadline(time.Now().Add(time.Second))
+
+		data, _ := io.ReadAll(conn)
+
+		return string(data)
+	}
+
+	source.set(docker.PortForward{Protocol: "tcp", ListenPort: port, ContainerID: "a", Target: tcpServer(t, "first")})
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, "first", read(c))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the container got another address
+	source.set(docker.PortForward{Protocol: "tcp", ListenPort: port, ContainerID: "a", Target: tcpServer(t, "second")})
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, "second", read(c))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the container is gone
+	source.set()
+
+	assert.Eventually(t, func() bool {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			_ = conn.Close()
+		}
+
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestForwarder_UDP(t *testing.T) {
+	t.Parallel()
+
+	var (
+		port   = freePort(t)
+		addr   = net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port)))
+		source = newFakeSource()
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() { _ = forward.New(zap.NewNop(), source, "127.0.0.1").Run(ctx) }()
+
+	conn, err := net.Dial("udp", addr)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var ping = func(c *assert.CollectT) string {
+		_, writeErr := conn.Write([]byte("ping"))
+		require.NoError(c, writeErr)
+
+		_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+		var buf = make([]byte, 1024)
+
+		n, readErr := conn.Read(buf)
+		require.NoError(c, readErr)
+
+		return string(buf[:n])
+	}
+
+	source.set(docker.PortForward{Protocol: "udp", ListenPort: port, ContainerID: "a", Target: udpServer(t, "first:")})
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, "first:ping", ping(c))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the same client is forwarded to the new address
+	source.set(docker.PortForward{Protocol: "udp", ListenPort: port, ContainerID: "a", Target: udpServer(t, "second:")})
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, "second:ping", ping(c))
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,144 @@
+package forward
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// tcpListener forwards the accepted TCP connections to the target.
+type tcpListener struct {
+	log    *zap.Logger
+	ln     net.Listener
+	target atomic.Pointer[string]
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{} // the forwarded connections (both sides)
+	closed bool
+}
+
+const (
+	dialTimeout      = 5 * time.Second       // maximum amount of time to connect to the target
+	acceptRetryDelay = 50 * time.Millisecond // delay before the next accept, after the temporary error
+)
+
+// listenTCP starts listening on the address, and forwarding the connections to the target.
+func listenTCP(log *zap.Logger, addr, target string) (*tcpListener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var l = &tcpListener{log: log, ln: ln, conns: make(map[net.Conn]struct{})}
+
+	l.target.Store(&target)
+
+	go l.serve()
+
+	return l, nil
+}
+
+// SetTarget implements the [listener] interface. The forwarded connections are not affected.
+func (l *tcpListener) SetTarget(addr string) { l.target.Store(&addr) }
+
+// Close implements the [listener] interface.
+func (l *tcpListener) Close() error {
+	var err = l.ln.Close()
+
+	l.mu.Lock()
+	for conn := range l.conns {
+		_ = conn.Close()
+	}
+
+	l.closed = true
+	l.mu.Unlock()
+
+	return err
+}
+
+// serve accepts the connections until the listener is closed.
+func (l *tcpListener) serve() {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			time.Sleep(acceptRetryDelay) // do not spin on the temporary errors (e.g. too many open files)
+
+			continue
+		}
+
+		go l.forward(conn)
+	}
+}
+
+// forward connects the client to the target, and copies the data in both directions until both sides are done.
+func (l *tcpListener) forward(client net.Conn) {
+	defer func() { _ = client.Close() }()
+
+	var target = *l.target.Load()
+
+	upstream, err := net.DialTimeout("tcp", target, dialTimeout)
+	if err != nil {
+		l.log.Warn("Failed to connect to the forwarded port", zap.String("target", target), zap.Error(err))
+
+		return
+	}
+
+	defer func() { _ = upstream.Close() }()
+
+	if !l.track(client, upstream) {
+		return // the listener is closed
+	}
+
+	defer l.untrack(client, upstream)
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() { _, _ = io.Copy(upstream, client); closeWrite(upstream) })
+	wg.Go(func() { _, _ = io.Copy(client, upstream); closeWrite(client) })
+
+	wg.Wait()
+}
+
+// track starts tracking the connections. It returns false if the listener is closed.
+func (l *tcpListener) track(conns ...net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return false
+	}
+
+	for _, conn := range conns {
+		l.conns[conn] = struct{}{}
+	}
+
+	return true
+}
+
+// untrack stops tracking the connections.
+func (l *tcpListener) untrack(conns ...net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, conn := range conns {
+		delete(l.conns, conn)
+	}
+}
+
+// closeWrite signals the peer that no more data will be sent (half-close), if the connection supports it.
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	} else {
+		_ = conn.Close()
+	}
+}
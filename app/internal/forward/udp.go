@@ -0,0 +1,169 @@
+package forward
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type (
+	// udpListener forwards the datagrams to the target. Every client (source address) gets its own upstream
+	// "connection" (session), so the replies are sent back to the right client.
+	udpListener struct {
+		log    *zap.Logger
+		conn   net.PacketConn
+		target atomic.Pointer[string]
+
+		mu       sync.Mutex
+		sessions map[string]*udpSession // map[client_address]session
+		closed   bool
+	}
+
+	// udpSession is the upstream connection of the single client.
+	udpSession struct {
+		client   net.Addr
+		upstream net.Conn
+	}
+)
+
+const (
+	udpSessionTimeout = 2 * time.Minute // the session is closed after this period of inactivity
+	udpMaxDatagram    = 64 * 1024       // maximum UDP datagram size
+)
+
+// listenUDP starts listening on the address, and forwarding the datagrams to the target.
+func listenUDP(log *zap.Logger, addr, target string) (*udpListener, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var l = &udpListener{log: log, conn: conn, sessions: make(map[string]*udpSession)}
+
+	l.target.Store(&target)
+
+	go l.serve()
+
+	return l, nil
+}
+
+// SetTarget implements the [listener] interface. The sessions are closed, so the next datagrams are sent to the new
+// target.
+func (l *udpListener) SetTarget(addr string) {
+	l.target.Store(&addr)
+
+	l.mu.Lock()
+	for client, s := range l.sessions {
+		_ = s.upstream.Close()
+
+		delete(l.sessions, client)
+	}
+	l.mu.Unlock()
+}
+
+// Close implements the [listener] interface.
+func (l *udpListener) Close() error {
+	var err = l.conn.Close()
+
+	l.mu.Lock()
+	for _, s := range l.sessions {
+		_ = s.upstream.Close()
+	}
+
+	l.closed = true
+	l.mu.Unlock()
+
+	return err
+}
+
+// serve reads the datagrams from the clients until the listener is closed.
+func (l *udpListener) serve() {
+	var buf = make([]byte, udpMaxDatagram)
+
+	for {
+		n, client, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		s, sErr := l.session(client)
+		if sErr != nil {
+			l.log.Warn("Failed to connect to the forwarded port", zap.Error(sErr))
+
+			continue
+		} else if s == nil {
+			return // the listener is closed
+		}
+
+		_ = s.upstream.SetReadDeadline(time.Now().Add(udpSessionTimeout)) // prolong the session
+
+		if _, err = s.upstream.Write(buf[:n]); err != nil {
+			l.closeSession(s)
+		}
+	}
+}
+
+// session returns the session of the client (a new one is started, if needed). It returns nil if the listener is
+// closed.
+func (l *udpListener) session(client net.Addr) (*udpSession, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, nil //nolint:nilnil
+	}
+
+	if s, ok := l.sessions[client.String()]; ok {
+		return s, nil
+	}
+
+	upstream, err := net.DialTimeout("udp", *l.target.Load(), dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var s = &udpSession{client: client, upstream: upstream}
+
+	l.sessions[client.String()] = s
+
+	go l.reply(s)
+
+	return s, nil
+}
+
+// reply sends the upstream datagrams back to the client, until the session is closed (or expired).
+func (l *udpListener) reply(s *udpSession) {
+	defer l.closeSession(s)
+
+	var buf = make([]byte, udpMaxDatagram)
+
+	for {
+		n, err := s.upstream.Read(buf)
+		if err != nil {
+			return
+		}
+
+		if _, err = l.conn.WriteTo(buf[:n], s.client); err != nil {
+			return
+		}
+	}
+}
+
+// closeSession closes the session, and forgets it (if it's still the current session of the client).
+func (l *udpListener) closeSession(s *udpSession) {
+	_ = s.upstream.Close()
+
+	l.mu.Lock()
+	if current, ok := l.sessions[s.client.String()]; ok && current == s {
+		delete(l.sessions, s.client.String())
+	}
+	l.mu.Unlock()
+}
@@ -0,0 +1,129 @@
+package container_action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type (
+	dockerClient interface {
+		ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+		ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+		ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+		ContainerRestart(ctx context.Context, containerID string, options container.StopOptions) error
+		ContainerPause(ctx context.Context, containerID string) error
+		ContainerUnpause(ctx context.Context, containerID string) error
+	}
+
+	state interface {
+		docker.ManagedContainerChecker
+		docker.StateUpdater
+	}
+
+	Handler struct {
+		log      *zap.Logger
+		dc       dockerClient
+		state    state
+		readOnly bool
+	}
+)
+
+var (
+	// ErrReadOnly is returned when the API is in read-only mode.
+	ErrReadOnly = errors.New("container actions are disabled (the API is in read-only mode)")
+
+	// ErrUnknownAction is returned when the requested action is not supported.
+	ErrUnknownAction = errors.New("unknown action")
+)
+
+// New is a constructor for the [Handler] structure. If readOnly is true, all actions will be rejected.
+func New(log *zap.Logger, dc dockerClient, state state, readOnly bool) *Handler {
+	return &Handler{log: log, dc: dc, state: state, readOnly: readOnly}
+}
+
+// Handle performs the action on the container, managed by indocker (see [docker.State.IsManagedContainer]). Once
+// the action is done, the docker state is updated immediately (without waiting for the docker events), so the routes
+// feed reflects the changes.
+func (h *Handler) Handle(
+	r *http.Request,
+	containerIDOrName string,
+	action openapi.ContainerAction,
+) (*openapi.ContainerActionResponse, error) {
+	if h.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	if !action.Valid() {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAction, action)
+	}
+
+	var ctx = r.Context()
+
+	info, inspectErr := h.dc.ContainerInspect(ctx, containerIDOrName)
+	if inspectErr != nil {
+		if errdefs.IsNotFound(inspectErr) {
+			return nil, docker.ErrContainerNotManaged
+		}
+
+		return nil, fmt.Errorf("failed to inspect the container: %w", inspectErr)
+	}
+
+	if !h.state.IsManagedContainer(info) {
+		return nil, docker.ErrContainerNotManaged
+	}
+
+	h.log.Info("Container action requested",
+		zap.String("action", string(action)),
+		zap.String("container id", info.ID),
+		zap.String("container name", info.Name),
+		zap.String("remote addr", r.RemoteAddr),
+	)
+
+	var actionErr error
+
+	switch action {
+	case openapi.ContainerActionStart:
+		actionErr = h.dc.ContainerStart(ctx, info.ID, container.StartOptions{})
+	case openapi.ContainerActionStop:
+		actionErr = h.dc.ContainerStop(ctx, info.ID, container.StopOptions{})
+	case openapi.ContainerActionRestart:
+		actionErr = h.dc.ContainerRestart(ctx, info.ID, container.StopOptions{})
+	case openapi.ContainerActionPause:
+		actionErr = h.dc.ContainerPause(ctx, info.ID)
+	case openapi.ContainerActionUnpause:
+		actionErr = h.dc.ContainerUnpause(ctx, info.ID)
+	}
+
+	if actionErr != nil {
+		h.log.Warn("Container action failed",
+			zap.String("action", string(action)),
+			zap.String("container id", info.ID),
+			zap.String("remote addr", r.RemoteAddr),
+			zap.Error(actionErr),
+		)
+
+		return nil, fmt.Errorf("failed to %s the container: %w", action, actionErr)
+	}
+
+	// do not wait for the docker events - update the state right now
+	if err := h.state.Update(ctx); err != nil {
+		h.log.Warn("Failed to update the docker state", zap.Error(err))
+	}
+
+	var resp = openapi.ContainerActionResponse{Id: info.ID, Action: action}
+
+	if updated, err := h.dc.ContainerInspect(ctx, info.ID); err == nil && updated.State != nil {
+		resp.State = string(updated.State.Status)
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,120 @@
+package container_action_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_action"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type fakeDockerClient struct {
+	labels map[string]string
+	status container.ContainerState
+	called []string
+}
+
+func (f *fakeDockerClient) ContainerInspect(context.Context, string) (container.InspectResponse, error) {
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "abc",
+			Name:  "/foo",
+			State: &container.State{Status: f.status},
+		},
+		Config: &container.Config{Labels: f.labels},
+	}, nil
+}
+
+func (f *fakeDockerClient) ContainerStart(context.Context, string, container.StartOptions) error {
+	f.called, f.status = append(f.called, "start"), container.StateRunning
+
+	return nil
+}
+
+func (f *fakeDockerClient) ContainerStop(context.Context, string, container.StopOptions) error {
+	f.called, f.status = append(f.called, "stop"), container.StateExited
+
+	return nil
+}
+
+func (f *fakeDockerClient) ContainerRestart(context.Context, string, container.StopOptions) error {
+	f.called = append(f.called, "restart")
+
+	return nil
+}
+
+func (f *fakeDockerClient) ContainerPause(context.Context, string) error {
+	f.called = append(f.called, "pause")
+
+	return nil
+}
+
+func (f *fakeDockerClient) ContainerUnpause(context.Context, string) error {
+	f.called = append(f.called, "unpause")
+
+	return nil
+}
+
+type fakeState struct{ updated int }
+
+func (*fakeState) IsManagedContainer(info container.InspectResponse) bool {
+	return info.Config != nil && info.Config.Labels["indocker.host"] != ""
+}
+
+func (f *fakeState) Update(context.Context) error { f.updated++; return nil }
+
+func newRequest() *http.Request { return httptest.NewRequest(http.MethodPost, "/", http.NoBody) }
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dc      = &fakeDockerClient{labels: map[string]string{"indocker.host": "foo"}, status: container.StateExited}
+		state   = &fakeState{}
+		handler = container_action.New(zap.NewNop(), dc, state, false)
+	)
+
+	resp, err := handler.Handle(newRequest(), "foo", openapi.ContainerActionStart)
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc", resp.Id)
+	assert.Equal(t, openapi.ContainerActionStart, resp.Action)
+	assert.Equal(t, "running", resp.State)
+	assert.Equal(t, []string{"start"}, dc.called)
+	assert.Equal(t, 1, state.updated)
+
+	_, err = handler.Handle(newRequest(), "foo", "explode")
+	assert.ErrorIs(t, err, container_action.ErrUnknownAction)
+}
+
+func TestHandler_HandleRejected(t *testing.T) {
+	t.Parallel()
+
+	t.Run("read-only", func(t *testing.T) {
+		var dc = &fakeDockerClient{labels: map[string]string{"indocker.host": "foo"}}
+
+		_, err := container_action.New(zap.NewNop(), dc, &fakeState{}, true).
+			Handle(newRequest(), "foo", openapi.ContainerActionStop)
+
+		assert.ErrorIs(t, err, container_action.ErrReadOnly)
+		assert.Empty(t, dc.called)
+	})
+
+	t.Run("not managed", func(t *testing.T) {
+		var dc = &fakeDockerClient{labels: map[string]string{"foo": "bar"}}
+
+		_, err := container_action.New(zap.NewNop(), dc, &fakeState{}, false).
+			Handle(newRequest(), "foo", openapi.ContainerActionStop)
+
+		assert.ErrorIs(t, err, docker.ErrContainerNotManaged)
+		assert.Empty(t, dc.called)
+	})
+}
@@ -0,0 +1,63 @@
+package container_diagnose
+
+import (
+	"context"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+type (
+	router interface {
+		docker.RoutingURLResolver
+		docker.RouteOptionsResolver
+	}
+
+	Handler struct {
+		diagnoser docker.ContainerDiagnoser
+		router    router
+	}
+)
+
+// New is a constructor for the [Handler] structure.
+func New(diagnoser docker.ContainerDiagnoser, router router) *Handler {
+	return &Handler{diagnoser: diagnoser, router: router}
+}
+
+// Handle explains why the container with the given ID or name is (or is not) routed.
+func (h *Handler) Handle(ctx context.Context, containerIDOrName string) (*openapi.ContainerDiagnosis, error) {
+	diagnosis, err := h.diagnoser.Diagnose(ctx, containerIDOrName)
+	if err != nil {
+		return nil, err
+	}
+
+	routing.ExplainOverride(diagnosis, h.router) // the hostname may be taken by another routes provider
+
+	var resp = openapi.ContainerDiagnosis{
+		ContainerId:   diagnosis.ContainerID,
+		ContainerName: diagnosis.ContainerName,
+		Routed:        diagnosis.Routed,
+		Steps:         make([]openapi.DiagnosticStep, 0, len(diagnosis.Steps)),
+	}
+
+	if diagnosis.Hostname != "" {
+		resp.Hostname = &diagnosis.Hostname
+	}
+
+	if diagnosis.URL != nil {
+		var u = diagnosis.URL.String()
+
+		resp.Url = &u
+	}
+
+	for _, step := range diagnosis.Steps {
+		resp.Steps = append(resp.Steps, openapi.DiagnosticStep{
+			Name:    step.Name,
+			Level:   openapi.DiagnosticStepLevel(step.Level),
+			Message: step.Message,
+		})
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,96 @@
+package container_diagnose_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_diagnose"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type fakeDiagnoser struct{ diagnosis *docker.Diagnosis }
+
+func (f fakeDiagnoser) Diagnose(context.Context, string) (*docker.Diagnosis, error) {
+	if f.diagnosis == nil {
+		return nil, docker.ErrContainerNotFound
+	}
+
+	return f.diagnosis, nil
+}
+
+type fakeRouter struct {
+	routes  docker.RoutesMap
+	options map[string]docker.RouteOptions
+}
+
+func (f fakeRouter) URLToContainerByHostname(hostname string) (docker.ContainerMap, bool) {
+	m, ok := f.routes[hostname]
+
+	return m, ok
+}
+
+func (f fakeRouter) RouteOptions(targetID string) (docker.RouteOptions, bool) {
+	o, ok := f.options[targetID]
+
+	return o, ok
+}
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	resp, err := container_diagnose.New(fakeDiagnoser{diagnosis: &docker.Diagnosis{
+		ContainerID:   "abc",
+		ContainerName: "foo",
+		Routed:        true,
+		Hostname:      "foo",
+		URL:           &url.URL{Scheme: "http", Host: "172.17.0.2:80"},
+		Steps: []docker.DiagnosticStep{
+			{Name: "host label", Level: docker.DiagnosticOK, Message: "found"},
+			{Name: "port label", Level: docker.DiagnosticWarn, Message: "not set"},
+		},
+	}}, fakeRouter{
+		routes: docker.RoutesMap{"foo": {"abc": {Scheme: "http", Host: "172.17.0.2:80"}}},
+	}).Handle(context.Background(), "foo")
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc", resp.ContainerId)
+	assert.Equal(t, "foo", resp.ContainerName)
+	assert.True(t, resp.Routed)
+	assert.Equal(t, "foo", *resp.Hostname)
+	assert.Equal(t, "http://172.17.0.2:80", *resp.Url)
+	assert.Equal(t, []openapi.DiagnosticStep{
+		{Name: "host label", Level: openapi.DiagnosticStepLevelOk, Message: "found"},
+		{Name: "port label", Level: openapi.DiagnosticStepLevelWarn, Message: "not set"},
+	}, resp.Steps)
+
+	_, err = container_diagnose.New(fakeDiagnoser{}, fakeRouter{}).Handle(context.Background(), "bar")
+	assert.ErrorIs(t, err, docker.ErrContainerNotFound)
+}
+
+func TestHandler_HandleOverridden(t *testing.T) {
+	t.Parallel()
+
+	resp, err := container_diagnose.New(fakeDiagnoser{diagnosis: &docker.Diagnosis{
+		ContainerID: "abc",
+		Routed:      true,
+		Hostname:    "foo",
+		URL:         &url.URL{Scheme: "http", Host: "172.17.0.2:80"},
+	}}, fakeRouter{
+		routes:  docker.RoutesMap{"foo": {"file:foo": {Scheme: "http", Host: "10.0.0.1:8080"}}},
+		options: map[string]docker.RouteOptions{"file:foo": {Source: docker.RouteSourceFile}},
+	}).Handle(context.Background(), "foo")
+	require.NoError(t, err)
+
+	assert.False(t, resp.Routed)
+	assert.Nil(t, resp.Url)
+	assert.Equal(t, []openapi.DiagnosticStep{{
+		Name:    "route provider",
+		Level:   openapi.DiagnosticStepLevelError,
+		Message: `the hostname "foo" is overridden by the route from the file provider`,
+	}}, resp.Steps)
+}
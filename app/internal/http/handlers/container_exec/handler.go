@@ -0,0 +1,222 @@
+package container_exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type (
+	dockerClient interface {
+		ContainerExecCreate(ctx context.Context, id string, o container.ExecOptions) (container.ExecCreateResponse, error)
+		ContainerExecAttach(ctx context.Context, execID string, o container.ExecAttachOptions) (types.HijackedResponse, error)
+		ContainerExecResize(ctx context.Context, execID string, options container.ResizeOptions) error
+		ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+	}
+
+	Handler struct {
+		log      *zap.Logger
+		dc       dockerClient
+		resolver docker.RoutedContainerResolver
+		readOnly bool
+		upgrader websocket.Upgrader
+	}
+
+	// inputMessage is a message from the client (see the ContainerExecInput schema in the OpenAPI spec).
+	inputMessage struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+		Cols uint   `json:"cols"`
+		Rows uint   `json:"rows"`
+	}
+)
+
+const defaultCommand = "/bin/sh"
+
+// ErrReadOnly is returned when the API is in read-only mode.
+var ErrReadOnly = errors.New("running commands in the containers is disabled (the API is in read-only mode)")
+
+// New is a constructor for the [Handler] structure. If readOnly is true, all requests will be rejected.
+func New(log *zap.Logger, dc dockerClient, resolver docker.RoutedContainerResolver, readOnly bool) *Handler {
+	return &Handler{log: log, dc: dc, resolver: resolver, readOnly: readOnly}
+}
+
+// Handle is a function that handles the WebSocket connection. It runs the command with a TTY in the container,
+// routed by indocker, and connects the command stdin/stdout to the WebSocket connection.
+func (h *Handler) Handle(
+	w http.ResponseWriter,
+	r *http.Request,
+	containerIDOrPrefix string,
+	params openapi.ExecInContainerParams,
+) error {
+	if h.readOnly {
+		return ErrReadOnly
+	}
+
+	containerID, found := h.resolver.RoutedContainerID(containerIDOrPrefix)
+	if !found {
+		return docker.ErrContainerNotRouted
+	}
+
+	var cmd = []string{defaultCommand}
+
+	if params.Cmd != nil && len(*params.Cmd) > 0 && (*params.Cmd)[0] != "" {
+		cmd = *params.Cmd
+	}
+
+	// upgrade the connection to the WebSocket first, so the exec instance is not left behind if the upgrade fails
+	ws, upgErr := h.upgrader.Upgrade(w, r, http.Header{})
+	if upgErr != nil {
+		return fmt.Errorf("failed to upgrade the connection: %w", upgErr)
+	}
+
+	defer func() { _ = ws.Close() }()
+
+	// create a new context for the request
+	var ctx, cancel = context.WithCancel(r.Context())
+	defer cancel()
+
+	created, createErr := h.dc.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if createErr != nil {
+		closeWithError(ws, fmt.Errorf("failed to create the exec instance: %w", createErr))
+
+		return nil
+	}
+
+	hijacked, attachErr := h.dc.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{Tty: true})
+	if attachErr != nil {
+		closeWithError(ws, fmt.Errorf("failed to attach to the exec instance: %w", attachErr))
+
+		return nil
+	}
+
+	defer hijacked.Close()
+
+	h.log.Info("Interactive command started",
+		zap.String("container id", containerID),
+		zap.Strings("command", cmd),
+		zap.String("remote addr", r.RemoteAddr),
+	)
+
+	// read messages from the client in a separate goroutine (and pass them to the command) and cancel the context
+	// when the connection is closed or an error occurs
+	go func() { defer cancel(); _ = h.reader(ctx, ws, hijacked.Conn, created.ID) }()
+
+	// close the hijacked connection when the context is canceled (to unblock the output reading)
+	go func() { <-ctx.Done(); hijacked.Close() }()
+
+	// copy the command output to the client, until the command exits
+	var copyErr = h.writer(ws, hijacked.Reader)
+
+	if copyErr == nil {
+		// the command has exited, let the client know the exit code (the context may be already canceled)
+		if inspect, err := h.dc.ContainerExecInspect(context.WithoutCancel(ctx), created.ID); err == nil {
+			_ = ws.WriteJSON(openapi.ContainerExecExit{ExitCode: inspect.ExitCode})
+		}
+	}
+
+	_ = ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
+	)
+
+	h.log.Info("Interactive command finished",
+		zap.String("container id", containerID),
+		zap.String("remote addr", r.RemoteAddr),
+	)
+
+	return nil
+}
+
+// closeWithError closes the WebSocket connection with the error (the HTTP error response can't be sent after the
+// connection is upgraded).
+func closeWithError(ws *websocket.Conn, err error) {
+	_ = ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()),
+		time.Now().Add(time.Second),
+	)
+}
+
+// reader is a function that reads messages from the client and passes them to the command (stdin data) or to the
+// docker API (terminal resize). It must be run in a separate goroutine to prevent blocking.
+func (h *Handler) reader(ctx context.Context, ws *websocket.Conn, stdin io.Writer, execID string) error {
+	for {
+		if ctx.Err() != nil { // check if the context is canceled
+			return nil
+		}
+
+		var messageType, data, msgErr = ws.ReadMessage()
+		if msgErr != nil {
+			return msgErr
+		}
+
+		switch messageType {
+		case websocket.BinaryMessage: // raw stdin data
+			if _, err := stdin.Write(data); err != nil {
+				return err
+			}
+
+		case websocket.TextMessage:
+			var msg inputMessage
+
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue // ignore malformed messages
+			}
+
+			switch msg.Type {
+			case "stdin":
+				if _, err := io.WriteString(stdin, msg.Data); err != nil {
+					return err
+				}
+
+			case "resize":
+				if msg.Cols > 0 && msg.Rows > 0 {
+					_ = h.dc.ContainerExecResize(ctx, execID, container.ResizeOptions{Height: msg.Rows, Width: msg.Cols})
+				}
+			}
+
+		case websocket.CloseMessage:
+			return nil // client closed the connection
+		}
+	}
+}
+
+// writer copies the command output to the client as binary messages. It returns nil when the command exits.
+func (h *Handler) writer(ws *websocket.Conn, stdout io.Reader) error {
+	var buf = make([]byte, 32*1024) //nolint:mnd
+
+	for {
+		n, readErr := stdout.Read(buf)
+		if n > 0 {
+			if err := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+				return err
+			}
+		}
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return nil
+			}
+
+			return readErr
+		}
+	}
+}
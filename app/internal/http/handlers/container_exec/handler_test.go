@@ -0,0 +1,191 @@
+package container_exec_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_exec"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+// fakeDockerClient runs the "command", which replies "pong" to "ping" and exits with the code 3.
+type fakeDockerClient struct {
+	attachErr error
+
+	mu      sync.Mutex
+	gotCmd  []string
+	resized []container.ResizeOptions
+}
+
+func (f *fakeDockerClient) ContainerExecCreate(
+	_ context.Context, _ string, o container.ExecOptions,
+) (container.ExecCreateResponse, error) {
+	f.mu.Lock()
+	f.gotCmd = o.Cmd
+	f.mu.Unlock()
+
+	return container.ExecCreateResponse{ID: "exec-id"}, nil
+}
+
+func (f *fakeDockerClient) ContainerExecAttach(
+	context.Context, string, container.ExecAttachOptions,
+) (types.HijackedResponse, error) {
+	if f.attachErr != nil {
+		return types.HijackedResponse{}, f.attachErr
+	}
+
+	var client, process = net.Pipe()
+
+	go func() {
+		defer func() { _ = process.Close() }()
+
+		var buf = make([]byte, len("ping"))
+
+		if _, err := io.ReadFull(process, buf); err == nil && string(buf) == "ping" {
+			_, _ = process.Write([]byte("pong"))
+		}
+	}()
+
+	return types.HijackedResponse{Conn: client, Reader: bufio.NewReader(client)}, nil
+}
+
+func (f *fakeDockerClient) ContainerExecResize(_ context.Context, _ string, o container.ResizeOptions) error {
+	f.mu.Lock()
+	f.resized = append(f.resized, o)
+	f.mu.Unlock()
+
+	return nil
+}
+
+func (*fakeDockerClient) ContainerExecInspect(context.Context, string) (container.ExecInspect, error) {
+	return container.ExecInspect{ExitCode: 3}, nil
+}
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) RoutedContainerID(id string) (string, bool) { v, ok := f[id]; return v, ok }
+
+// serve starts the server, which handles the WebSocket connections using the handler.
+func serve(t *testing.T, handler *container_exec.Handler, params openapi.ExecInContainerParams) string {
+	t.Helper()
+
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, handler.Handle(w, r, "abc", params))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveCmd *[]string
+		wantCmd []string
+	}{
+		"default command": {wantCmd: []string{"/bin/sh"}},
+		"quoted argument": {giveCmd: &[]string{"sh", "-c", "ls -la /"}, wantCmd: []string{"sh", "-c", "ls -la /"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				dc      = &fakeDockerClient{}
+				handler = container_exec.New(zap.NewNop(), dc, fakeResolver{"abc": "abcdef"}, false)
+			)
+
+			ws, _, err := websocket.DefaultDialer.Dial(serve(t, handler, openapi.ExecInContainerParams{Cmd: tt.giveCmd}), nil)
+			require.NoError(t, err)
+
+			defer func() { _ = ws.Close() }()
+
+			require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"resize","cols":80,"rows":24}`)))
+			require.NoError(t, ws.WriteMessage(websocket.BinaryMessage, []byte("ping")))
+
+			msgType, data, err := ws.ReadMessage()
+			require.NoError(t, err)
+			assert.Equal(t, websocket.BinaryMessage, msgType)
+			assert.Equal(t, "pong", string(data))
+
+			var exit openapi.ContainerExecExit
+
+			require.NoError(t, ws.ReadJSON(&exit))
+			assert.Equal(t, 3, exit.ExitCode)
+
+			_, _, err = ws.ReadMessage()
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err.Error())
+
+			dc.mu.Lock()
+			defer dc.mu.Unlock()
+
+			assert.Equal(t, tt.wantCmd, dc.gotCmd)
+			assert.Equal(t, []container.ResizeOptions{{Height: 24, Width: 80}}, dc.resized)
+		})
+	}
+}
+
+func TestHandler_HandleAttachError(t *testing.T) {
+	t.Parallel()
+
+	var handler = container_exec.New(zap.NewNop(), &fakeDockerClient{attachErr: errors.New("boom")},
+		fakeResolver{"abc": "abcdef"}, false,
+	)
+
+	ws, _, err := websocket.DefaultDialer.Dial(serve(t, handler, openapi.ExecInContainerParams{}), nil)
+	require.NoError(t, err)
+
+	defer func() { _ = ws.Close() }()
+
+	_, _, err = ws.ReadMessage()
+
+	var closeErr *websocket.CloseError
+
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.CloseInternalServerErr, closeErr.Code)
+	assert.Contains(t, closeErr.Text, "failed to attach to the exec instance: boom")
+}
+
+func TestHandler_HandleRejected(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveReadOnly bool
+		giveResolver fakeResolver
+		wantErr      error
+	}{
+		"read-only": {
+			giveReadOnly: true, giveResolver: fakeResolver{"abc": "abcdef"}, wantErr: container_exec.ErrReadOnly,
+		},
+		"not routed": {giveResolver: fakeResolver{}, wantErr: docker.ErrContainerNotRouted},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var handler = container_exec.New(zap.NewNop(), &fakeDockerClient{}, tt.giveResolver, tt.giveReadOnly)
+
+			var err = handler.Handle(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody),
+				"abc", openapi.ExecInContainerParams{},
+			)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package container_logs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type (
+	dockerClient interface {
+		ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+		ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+	}
+
+	Handler struct {
+		dc       dockerClient
+		resolver docker.RoutedContainerResolver
+		upgrader websocket.Upgrader
+	}
+)
+
+// ErrNoStreams is returned when both the stdout and stderr streams are deselected.
+var ErrNoStreams = errors.New("at least one of the stdout or stderr streams must be selected")
+
+// New is a constructor for the [Handler] structure.
+func New(dc dockerClient, resolver docker.RoutedContainerResolver) *Handler {
+	return &Handler{dc: dc, resolver: resolver}
+}
+
+// Handle is a function that handles the WebSocket connection. It streams the logs of the container to the client
+// in [openapi.ContainerLogLine] format (one message per line).
+func (h *Handler) Handle(
+	w http.ResponseWriter,
+	r *http.Request,
+	containerIDOrPrefix string,
+	params openapi.StreamContainerLogsParams,
+) error {
+	// only containers routed by indocker are allowed
+	containerID, found := h.resolver.RoutedContainerID(containerIDOrPrefix)
+	if !found {
+		return docker.ErrContainerNotRouted
+	}
+
+	var opts = container.LogsOptions{Follow: true, Tail: "100", ShowStdout: true, ShowStderr: true}
+
+	if params.Follow != nil {
+		opts.Follow = *params.Follow
+	}
+
+	if params.Tail != nil {
+		opts.Tail = *params.Tail
+	}
+
+	if params.Since != nil {
+		opts.Since = *params.Since
+	}
+
+	if params.Stdout != nil {
+		opts.ShowStdout = *params.Stdout
+	}
+
+	if params.Stderr != nil {
+		opts.ShowStderr = *params.Stderr
+	}
+
+	if !opts.ShowStdout && !opts.ShowStderr {
+		return ErrNoStreams
+	}
+
+	// we need to know if the container uses a TTY (in this case the logs stream is not multiplexed)
+	info, inspectErr := h.dc.ContainerInspect(r.Context(), containerID)
+	if inspectErr != nil {
+		return fmt.Errorf("failed to inspect the container: %w", inspectErr)
+	}
+
+	// create a new context for the request
+	var ctx, cancel = context.WithCancel(r.Context())
+	defer cancel()
+
+	logs, logsErr := h.dc.ContainerLogs(ctx, containerID, opts)
+	if logsErr != nil {
+		return fmt.Errorf("failed to read the container logs: %w", logsErr)
+	}
+
+	defer func() { _ = logs.Close() }()
+
+	// upgrade the connection to the WebSocket
+	ws, upgErr := h.upgrader.Upgrade(w, r, http.Header{})
+	if upgErr != nil {
+		return fmt.Errorf("failed to upgrade the connection: %w", upgErr)
+	}
+
+	defer func() { _ = ws.Close() }()
+
+	// read messages from the client in a separate goroutine and cancel the context when the connection is closed or
+	// an error occurs
+	go func() { defer cancel(); _ = h.reader(ctx, ws) }()
+
+	// ping the client periodically, until the context is canceled
+	go h.pinger(ctx, ws)
+
+	var (
+		mu      sync.Mutex // the websocket connection does not support concurrent writers
+		stdout  = &lineWriter{ws: ws, mu: &mu, stream: openapi.ContainerLogLineStreamStdout}
+		stderr  = &lineWriter{ws: ws, mu: &mu, stream: openapi.ContainerLogLineStreamStderr}
+		copyErr error
+	)
+
+	if info.Config != nil && info.Config.Tty {
+		_, copyErr = io.Copy(stdout, logs) // the stream is raw, without multiplexing
+	} else {
+		_, copyErr = stdcopy.StdCopy(stdout, stderr, logs) // demultiplex the stream
+	}
+
+	// send the rest of the buffered data (the last line without a line break)
+	_ = stdout.Flush()
+	_ = stderr.Flush()
+
+	var closeMsg = websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+
+	if copyErr != nil && ctx.Err() == nil {
+		closeMsg = websocket.FormatCloseMessage(websocket.CloseInternalServerErr, copyErr.Error())
+	}
+
+	_ = ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+
+	return nil
+}
+
+// reader is a function that reads messages from the client. It must be run in a separate goroutine to prevent
+// blocking. This function will exit when the context is canceled, the client closes the connection, or an error
+// during the reading occurs.
+func (h *Handler) reader(ctx context.Context, ws *websocket.Conn) error {
+	for {
+		if ctx.Err() != nil { // check if the context is canceled
+			return nil
+		}
+
+		var messageType, msgReader, msgErr = ws.NextReader()
+		if msgErr != nil {
+			return msgErr
+		}
+
+		if msgReader != nil {
+			_, _ = io.Copy(io.Discard, msgReader) // ignore the message body but read it to prevent potential memory leaks
+		}
+
+		if messageType == websocket.CloseMessage {
+			return nil // client closed the connection
+		}
+	}
+}
+
+// pinger sends ping messages to the client periodically, until the context is canceled.
+func (h *Handler) pinger(ctx context.Context, ws *websocket.Conn) {
+	var pingTicker = time.NewTicker(10 * time.Second) //nolint:mnd
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pingTicker.C:
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil { //nolint:mnd
+				return
+			}
+		}
+	}
+}
+
+// lineWriter is an [io.Writer] that splits the written data into lines and sends every line to the WebSocket
+// connection as a separate message.
+type lineWriter struct {
+	ws     *websocket.Conn
+	mu     *sync.Mutex // shared between writers of the same connection
+	stream openapi.ContainerLogLineStream
+	buf    []byte
+}
+
+var _ io.Writer = (*lineWriter)(nil) // verify interface implementation
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf = append(lw.buf, p...)
+
+	for {
+		var idx = bytes.IndexByte(lw.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		var line = bytes.TrimRight(lw.buf[:idx], "\r")
+
+		if err := lw.send(line); err != nil {
+			return 0, err
+		}
+
+		lw.buf = lw.buf[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush sends the buffered data (if any) as a separate line.
+func (lw *lineWriter) Flush() error {
+	if len(lw.buf) == 0 {
+		return nil
+	}
+
+	defer func() { lw.buf = lw.buf[:0] }()
+
+	return lw.send(lw.buf)
+}
+
+func (lw *lineWriter) send(line []byte) error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	return lw.ws.WriteJSON(openapi.ContainerLogLine{Stream: lw.stream, Line: string(line)})
+}
@@ -0,0 +1,162 @@
+package container_logs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_logs"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type fakeDockerClient struct {
+	tty       bool
+	logs      []byte
+	inspected bool
+	gotOpts   container.LogsOptions
+}
+
+func (f *fakeDockerClient) ContainerInspect(context.Context, string) (container.InspectResponse, error) {
+	f.inspected = true
+
+	return container.InspectResponse{Config: &container.Config{Tty: f.tty}}, nil
+}
+
+func (f *fakeDockerClient) ContainerLogs(_ context.Context, _ string, o container.LogsOptions) (io.ReadCloser, error) {
+	f.gotOpts = o
+
+	return io.NopCloser(bytes.NewReader(f.logs)), nil
+}
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) RoutedContainerID(id string) (string, bool) { v, ok := f[id]; return v, ok }
+
+func multiplexed(t *testing.T) []byte {
+	t.Helper()
+
+	var (
+		buf    bytes.Buffer
+		stdout = stdcopy.NewStdWriter(&buf, stdcopy.Stdout)
+		stderr = stdcopy.NewStdWriter(&buf, stdcopy.Stderr)
+	)
+
+	_, err := stdout.Write([]byte("first line\nsecond "))
+	require.NoError(t, err)
+	_, err = stderr.Write([]byte("oops\r\n"))
+	require.NoError(t, err)
+	_, err = stdout.Write([]byte("line\nno line break"))
+	require.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, url string) (lines []openapi.ContainerLogLine) {
+	t.Helper()
+
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+
+	defer func() { _ = ws.Close() }()
+
+	for {
+		var line openapi.ContainerLogLine
+
+		if err = ws.ReadJSON(&line); err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err.Error())
+
+			return
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveTTY   bool
+		giveLogs  []byte
+		wantLines []openapi.ContainerLogLine
+	}{
+		"multiplexed": {
+			giveLogs: multiplexed(t),
+			wantLines: []openapi.ContainerLogLine{
+				{Stream: openapi.ContainerLogLineStreamStdout, Line: "first line"},
+				{Stream: openapi.ContainerLogLineStreamStderr, Line: "oops"},
+				{Stream: openapi.ContainerLogLineStreamStdout, Line: "second line"},
+				{Stream: openapi.ContainerLogLineStreamStdout, Line: "no line break"},
+			},
+		},
+		"tty": {
+			giveTTY:  true,
+			giveLogs: []byte("foo\nbar\n"),
+			wantLines: []openapi.ContainerLogLine{
+				{Stream: openapi.ContainerLogLineStreamStdout, Line: "foo"},
+				{Stream: openapi.ContainerLogLineStreamStdout, Line: "bar"},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				dc      = &fakeDockerClient{tty: tt.giveTTY, logs: tt.giveLogs}
+				handler = container_logs.New(dc, fakeResolver{"abc": "abcdef"})
+				srv     = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					var tail = "5"
+
+					assert.NoError(t, handler.Handle(w, r, "abc", openapi.StreamContainerLogsParams{Tail: &tail}))
+				}))
+			)
+
+			defer srv.Close()
+
+			assert.Equal(t, tt.wantLines, readAll(t, "ws"+strings.TrimPrefix(srv.URL, "http")))
+			assert.Equal(t, "5", dc.gotOpts.Tail)
+			assert.True(t, dc.gotOpts.Follow)
+		})
+	}
+}
+
+func TestHandler_HandleNotRouted(t *testing.T) {
+	t.Parallel()
+
+	var handler = container_logs.New(&fakeDockerClient{}, fakeResolver{})
+
+	var err = handler.Handle(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody), "abc",
+		openapi.StreamContainerLogsParams{},
+	)
+
+	assert.ErrorIs(t, err, docker.ErrContainerNotRouted)
+}
+
+func TestHandler_HandleNoStreams(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dc      = &fakeDockerClient{}
+		handler = container_logs.New(dc, fakeResolver{"abc": "abc123"})
+		off     = false
+	)
+
+	var err = handler.Handle(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody), "abc",
+		openapi.StreamContainerLogsParams{Stdout: &off, Stderr: &off},
+	)
+
+	assert.ErrorIs(t, err, container_logs.ErrNoStreams)
+	assert.False(t, dc.inspected) // docker is not called at all
+	assert.Empty(t, dc.gotOpts.Tail)
+}
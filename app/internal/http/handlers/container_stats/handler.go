@@ -0,0 +1,47 @@
+package container_stats
+
+import (
+	"context"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type Handler struct {
+	dc       docker.StatsReader
+	resolver docker.RoutedContainerResolver
+}
+
+// New is a constructor for the [Handler] structure.
+func New(dc docker.StatsReader, resolver docker.RoutedContainerResolver) *Handler {
+	return &Handler{dc: dc, resolver: resolver}
+}
+
+// Handle returns the current resource usage of the container, routed by indocker.
+func (h *Handler) Handle(ctx context.Context, containerIDOrPrefix string) (*openapi.ContainerStatsResponse, error) {
+	containerID, found := h.resolver.RoutedContainerID(containerIDOrPrefix)
+	if !found {
+		return nil, docker.ErrContainerNotRouted
+	}
+
+	usage, err := docker.ContainerUsage(ctx, h.dc, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &openapi.ContainerStatsResponse{Id: containerID, Usage: UsageToResponse(usage)}, nil
+}
+
+// UsageToResponse converts the resource usage to the response format.
+func UsageToResponse(u docker.ResourceUsage) openapi.ResourceUsage {
+	return openapi.ResourceUsage{
+		CpuPercent:       u.CPUPercent,
+		MemoryUsageBytes: int64(u.MemoryUsage), //nolint:gosec
+		MemoryLimitBytes: int64(u.MemoryLimit), //nolint:gosec
+		MemoryPercent:    u.MemoryPercent(),
+		NetworkRxBytes:   int64(u.NetworkRx),  //nolint:gosec
+		NetworkTxBytes:   int64(u.NetworkTx),  //nolint:gosec
+		BlockReadBytes:   int64(u.BlockRead),  //nolint:gosec
+		BlockWriteBytes:  int64(u.BlockWrite), //nolint:gosec
+	}
+}
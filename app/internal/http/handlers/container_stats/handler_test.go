@@ -0,0 +1,81 @@
+package container_stats_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_stats"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+// fakeStatsReader returns the stats with the given memory usage (and the 1000 bytes limit) by the container ID.
+type fakeStatsReader map[string]uint64
+
+func (f fakeStatsReader) ContainerStats(_ context.Context, id string, _ bool) (container.StatsResponseReader, error) {
+	usage, ok := f[id]
+	if !ok {
+		return container.StatsResponseReader{}, errors.New("container is not running")
+	}
+
+	var buf bytes.Buffer
+
+	_ = json.NewEncoder(&buf).Encode(container.StatsResponse{
+		MemoryStats: container.MemoryStats{Usage: usage, Limit: 1000},
+	})
+
+	return container.StatsResponseReader{Body: io.NopCloser(&buf)}, nil
+}
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) RoutedContainerID(id string) (string, bool) { v, ok := f[id]; return v, ok }
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	var handler = container_stats.New(
+		fakeStatsReader{"abcdef": 250},
+		fakeResolver{"abc": "abcdef", "stopped": "stopped-id"},
+	)
+
+	for name, tt := range map[string]struct {
+		giveID    string
+		wantResp  *openapi.ContainerStatsResponse
+		wantErrIs error
+		wantErr   string
+	}{
+		"routed": {
+			giveID: "abc",
+			wantResp: &openapi.ContainerStatsResponse{Id: "abcdef", Usage: openapi.ResourceUsage{
+				MemoryUsageBytes: 250, MemoryLimitBytes: 1000, MemoryPercent: 25,
+			}},
+		},
+		"not routed":  {giveID: "foo", wantErrIs: docker.ErrContainerNotRouted},
+		"stats error": {giveID: "stopped", wantErr: "container is not running"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := handler.Handle(context.Background(), tt.giveID)
+
+			switch {
+			case tt.wantErrIs != nil:
+				assert.ErrorIs(t, err, tt.wantErrIs)
+			case tt.wantErr != "":
+				assert.ErrorContains(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantResp, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package container_stats_subscribe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_stats"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type Handler struct {
+	dc       docker.StatsReader
+	resolver docker.RoutedContainerResolver
+	upgrader websocket.Upgrader
+}
+
+// New is a constructor for the [Handler] structure.
+func New(dc docker.StatsReader, resolver docker.RoutedContainerResolver) *Handler {
+	return &Handler{dc: dc, resolver: resolver}
+}
+
+// Handle is a function that handles the WebSocket connection. It streams the resource usage of the container to
+// the client in [openapi.ContainerStats] format.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request, containerIDOrPrefix string) error {
+	containerID, found := h.resolver.RoutedContainerID(containerIDOrPrefix)
+	if !found {
+		return docker.ErrContainerNotRouted
+	}
+
+	// upgrade the connection to the WebSocket
+	ws, upgErr := h.upgrader.Upgrade(w, r, http.Header{})
+	if upgErr != nil {
+		return fmt.Errorf("failed to upgrade the connection: %w", upgErr)
+	}
+
+	defer func() { _ = ws.Close() }()
+
+	// create a new context for the request
+	var ctx, cancel = context.WithCancel(r.Context())
+	defer cancel()
+
+	// read messages from the client in a separate goroutine and cancel the context when the connection is closed or
+	// an error occurs
+	go func() { defer cancel(); _ = h.reader(ctx, ws) }()
+
+	// docker sends the stats every second, so there is no need to ping the client additionally
+	var streamErr = docker.StreamContainerUsage(ctx, h.dc, containerID, func(u docker.ResourceUsage) error {
+		return ws.WriteJSON(openapi.ContainerStats{Id: containerID, Usage: container_stats.UsageToResponse(u)})
+	})
+
+	var closeMsg = websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+
+	if streamErr != nil && ctx.Err() == nil {
+		closeMsg = websocket.FormatCloseMessage(websocket.CloseInternalServerErr, streamErr.Error())
+	}
+
+	_ = ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+
+	return nil
+}
+
+// reader is a function that reads messages from the client. It must be run in a separate goroutine to prevent
+// blocking. This function will exit when the context is canceled, the client closes the connection, or an error
+// during the reading occurs.
+func (h *Handler) reader(ctx context.Context, ws *websocket.Conn) error {
+	for {
+		if ctx.Err() != nil { // check if the context is canceled
+			return nil
+		}
+
+		var messageType, msgReader, msgErr = ws.NextReader()
+		if msgErr != nil {
+			return msgErr
+		}
+
+		if msgReader != nil {
+			_, _ = io.Copy(io.Discard, msgReader) // ignore the message body but read it to prevent potential memory leaks
+		}
+
+		if messageType == websocket.CloseMessage {
+			return nil // client closed the connection
+		}
+	}
+}
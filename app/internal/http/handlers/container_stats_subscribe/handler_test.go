@@ -0,0 +1,135 @@
+package container_stats_subscribe_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_stats_subscribe"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+// fakeStatsReader streams the stats with the given memory usages (and the 1000 bytes limit), then the stream is
+// closed (or broken, if the body is not valid JSON).
+type fakeStatsReader struct{ body []byte }
+
+func (f fakeStatsReader) ContainerStats(
+	_ context.Context, _ string, stream bool,
+) (container.StatsResponseReader, error) {
+	if !stream {
+		return container.StatsResponseReader{}, errors.New("streaming expected")
+	}
+
+	return container.StatsResponseReader{Body: io.NopCloser(bytes.NewReader(f.body))}, nil
+}
+
+func statsStream(t *testing.T, usages ...uint64) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	for _, u := range usages {
+		require.NoError(t, json.NewEncoder(&buf).Encode(container.StatsResponse{
+			MemoryStats: container.MemoryStats{Usage: u, Limit: 1000},
+		}))
+	}
+
+	return buf.Bytes()
+}
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) RoutedContainerID(id string) (string, bool) { v, ok := f[id]; return v, ok }
+
+// readAll reads the stats messages until the connection is closed, and returns the close code.
+func readAll(t *testing.T, url string) (stats []openapi.ContainerStats, closeCode int) {
+	t.Helper()
+
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+
+	defer func() { _ = ws.Close() }()
+
+	for {
+		var msg openapi.ContainerStats
+
+		if err = ws.ReadJSON(&msg); err != nil {
+			var closeErr *websocket.CloseError
+
+			require.ErrorAs(t, err, &closeErr)
+
+			return stats, closeErr.Code
+		}
+
+		stats = append(stats, msg)
+	}
+}
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveBody      []byte
+		wantMemory    []int64
+		wantCloseCode int
+	}{
+		"stream": {
+			giveBody:      statsStream(t, 100, 200, 300),
+			wantMemory:    []int64{100, 200, 300},
+			wantCloseCode: websocket.CloseNormalClosure,
+		},
+		"broken stream": {
+			giveBody:      append(statsStream(t, 100), []byte("{oops")...),
+			wantMemory:    []int64{100},
+			wantCloseCode: websocket.CloseInternalServerErr,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				handler = container_stats_subscribe.New(fakeStatsReader{body: tt.giveBody}, fakeResolver{"abc": "abcdef"})
+				srv     = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.NoError(t, handler.Handle(w, r, "abc"))
+				}))
+			)
+
+			defer srv.Close()
+
+			stats, closeCode := readAll(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+
+			var memory = make([]int64, 0, len(stats))
+
+			for _, s := range stats {
+				assert.Equal(t, "abcdef", s.Id)
+
+				memory = append(memory, s.Usage.MemoryUsageBytes)
+			}
+
+			assert.Equal(t, tt.wantMemory, memory)
+			assert.Equal(t, tt.wantCloseCode, closeCode)
+		})
+	}
+}
+
+func TestHandler_HandleNotRouted(t *testing.T) {
+	t.Parallel()
+
+	var handler = container_stats_subscribe.New(fakeStatsReader{}, fakeResolver{})
+
+	var err = handler.Handle(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody), "abc")
+
+	assert.ErrorIs(t, err, docker.ErrContainerNotRouted)
+}
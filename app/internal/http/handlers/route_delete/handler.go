@@ -0,0 +1,48 @@
+package route_delete
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/route_set"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+	"gh.tarampamp.am/indocker-app/app/internal/routestore"
+)
+
+type (
+	store interface {
+		Delete(hostname string) (routestore.Entry, error)
+	}
+
+	Handler struct {
+		log      *zap.Logger
+		store    store
+		readOnly bool
+	}
+)
+
+// ErrReadOnly is returned when the API is in read-only mode.
+var ErrReadOnly = errors.New("route changes are disabled (the API is in read-only mode)")
+
+// New is a constructor for the [Handler] structure. If readOnly is true, all changes will be rejected.
+func New(log *zap.Logger, store store, readOnly bool) *Handler {
+	return &Handler{log: log, store: store, readOnly: readOnly}
+}
+
+// Handle removes the runtime route. Routes from other providers (e.g. docker) can't be removed.
+func (h *Handler) Handle(r *http.Request, hostname string) (*openapi.RuntimeRoute, error) {
+	if h.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	deleted, err := h.store.Delete(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	h.log.Info("Runtime route removed", zap.String("hostname", deleted.Hostname), zap.String("remote addr", r.RemoteAddr))
+
+	return route_set.ToResponse(deleted), nil
+}
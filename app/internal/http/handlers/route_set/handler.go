@@ -0,0 +1,132 @@
+package route_set
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+	"gh.tarampamp.am/indocker-app/app/internal/routestore"
+)
+
+type (
+	store interface {
+		Create(routestore.Entry) (routestore.Entry, error)
+		Put(routestore.Entry) (routestore.Entry, error)
+	}
+
+	Handler struct {
+		log      *zap.Logger
+		store    store
+		readOnly bool
+		now      func() time.Time
+	}
+)
+
+var (
+	// ErrReadOnly is returned when the API is in read-only mode.
+	ErrReadOnly = errors.New("route changes are disabled (the API is in read-only mode)")
+
+	// ErrUnsupportedContentType is returned when the request body is not JSON. The JSON content type can't be set
+	// by the web page without the CORS preflight, so this also protects against the cross-site requests.
+	ErrUnsupportedContentType = errors.New("unsupported content type (application/json expected)")
+)
+
+// maxBodySize limits the request body size.
+const maxBodySize = 64 << 10 // 64 KiB
+
+// New is a constructor for the [Handler] structure. If readOnly is true, all changes will be rejected.
+func New(log *zap.Logger, store store, readOnly bool) *Handler {
+	return &Handler{log: log, store: store, readOnly: readOnly, now: time.Now}
+}
+
+// Handle adds the runtime route (if replace is true, the existing route with the same hostname is replaced).
+func (h *Handler) Handle(r *http.Request, hostname string, replace bool) (*openapi.RuntimeRoute, error) {
+	if h.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return nil, ErrUnsupportedContentType
+	}
+
+	var (
+		req openapi.RuntimeRouteRequest
+		dec = json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	)
+
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&req); err != nil {
+		return nil, fmt.Errorf("%w: wrong request body: %w", routestore.ErrInvalidRoute, err)
+	}
+
+	var entry = routestore.Entry{Hostname: hostname, URL: req.Url}
+
+	if req.PreserveHost != nil {
+		entry.PreserveHost = *req.PreserveHost
+	}
+
+	if req.Headers != nil {
+		entry.Headers = *req.Headers
+	}
+
+	if req.Ttl != nil && *req.Ttl != "" {
+		ttl, err := time.ParseDuration(*req.Ttl)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("%w: wrong TTL %q (positive duration like 30m or 2h expected)",
+				routestore.ErrInvalidRoute, *req.Ttl,
+			)
+		}
+
+		var expiresAt = h.now().Add(ttl).UTC()
+
+		entry.ExpiresAt = &expiresAt
+	}
+
+	var (
+		saved routestore.Entry
+		err   error
+	)
+
+	if replace {
+		saved, err = h.store.Put(entry)
+	} else {
+		saved, err = h.store.Create(entry)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	h.log.Info("Runtime route saved",
+		zap.String("hostname", saved.Hostname),
+		zap.String("url", saved.URL),
+		zap.Timep("expires at", saved.ExpiresAt),
+		zap.String("remote addr", r.RemoteAddr),
+	)
+
+	return ToResponse(saved), nil
+}
+
+// ToResponse converts the runtime route to the response.
+func ToResponse(e routestore.Entry) *openapi.RuntimeRoute {
+	var resp = openapi.RuntimeRoute{
+		Hostname:     e.Hostname,
+		Url:          e.URL,
+		PreserveHost: e.PreserveHost,
+		Headers:      e.Headers,
+		ExpiresAt:    e.ExpiresAt,
+	}
+
+	if resp.Headers == nil {
+		resp.Headers = make(map[string]string)
+	}
+
+	return &resp
+}
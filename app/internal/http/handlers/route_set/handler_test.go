@@ -0,0 +1,79 @@
+package route_set_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/route_delete"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/route_set"
+	"gh.tarampamp.am/indocker-app/app/internal/routestore"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	store, err := routestore.New(zap.NewNop())
+	require.NoError(t, err)
+
+	var (
+		set = route_set.New(zap.NewNop(), store, false)
+		req = func(body string) *http.Request {
+			var r = httptest.NewRequest(http.MethodPost, "/api/routes/vite", strings.NewReader(body))
+
+			r.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+			return r
+		}
+	)
+
+	var body = `{"url": "http://127.0.0.1:5173", "ttl": "1h", "headers": {"X-Foo": "bar"}}`
+
+	resp, err := set.Handle(req(body), "vite", false)
+	require.NoError(t, err)
+	assert.Equal(t, "vite", resp.Hostname)
+	assert.Equal(t, "http://127.0.0.1:5173", resp.Url)
+	assert.Equal(t, map[string]string{"X-Foo": "bar"}, resp.Headers)
+	assert.NotNil(t, resp.ExpiresAt)
+
+	_, err = set.Handle(req(`{"url": "http://127.0.0.1:5174"}`), "vite", false)
+	assert.ErrorIs(t, err, routestore.ErrRouteExists)
+
+	resp, err = set.Handle(req(`{"url": "http://127.0.0.1:5174"}`), "vite", true)
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:5174", resp.Url)
+	assert.Nil(t, resp.ExpiresAt)
+	assert.Empty(t, resp.Headers)
+
+	for _, invalid := range []string{`{"url": "http://foo", "ttl": "-1h"}`, `{"url": "http://foo", "foo": 1}`, `{`} {
+		_, err = set.Handle(req(invalid), "bar", true)
+		assert.ErrorIs(t, err, routestore.ErrInvalidRoute, invalid)
+	}
+
+	// not a JSON request (e.g. the cross-site form or text/plain request)
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		var r = req(`{"url": "http://foo"}`)
+
+		r.Header.Set("Content-Type", contentType)
+
+		_, err = set.Handle(r, "bar", true)
+		assert.ErrorIs(t, err, route_set.ErrUnsupportedContentType, contentType)
+	}
+
+	// read-only mode
+	_, err = route_set.New(zap.NewNop(), store, true).Handle(req(`{"url": "http://foo"}`), "bar", true)
+	assert.ErrorIs(t, err, route_set.ErrReadOnly)
+
+	_, err = route_delete.New(zap.NewNop(), store, true).Handle(req(""), "vite")
+	assert.ErrorIs(t, err, route_delete.ErrReadOnly)
+
+	// delete
+	resp, err = route_delete.New(zap.NewNop(), store, false).Handle(req(""), "vite")
+	require.NoError(t, err)
+	assert.Equal(t, "vite", resp.Hostname)
+}
@@ -0,0 +1,95 @@
+package route_stats
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_stats"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+type (
+	router interface {
+		docker.RoutingURLResolver
+		docker.RouteOptionsResolver
+	}
+
+	Handler struct {
+		dc     docker.StatsReader
+		router router
+	}
+)
+
+// ErrRouteNotFound is returned when the requested hostname is not routed.
+var ErrRouteNotFound = errors.New("hostname not found")
+
+// New is a constructor for the [Handler] structure.
+func New(dc docker.StatsReader, router router) *Handler {
+	return &Handler{dc: dc, router: router}
+}
+
+// Handle returns the resource usage of every container behind the route, and the aggregated (summarized) total.
+// The containers stats are requested concurrently. Route targets that are not containers (e.g. defined in the
+// routes file) and the sleeping autostart containers are skipped. The containers, whose stats can't be read (e.g. the
+// replica is stopping), are reported as failed, and excluded from the total.
+func (h *Handler) Handle(ctx context.Context, hostname string) (*openapi.RouteStatsResponse, error) {
+	urls, found := h.router.URLToContainerByHostname(hostname)
+	if !found || len(urls) == 0 {
+		return nil, ErrRouteNotFound
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		total  docker.ResourceUsage
+		failed []openapi.RouteStatsFailure
+		resp   = openapi.RouteStatsResponse{
+			Hostname:   hostname,
+			Containers: make([]openapi.ContainerStats, 0, len(urls)),
+		}
+	)
+
+	for containerID := range urls {
+		if opts, ok := h.router.RouteOptions(containerID); ok && (opts.Source != docker.RouteSourceDocker || opts.Sleeping) {
+			continue
+		}
+
+		wg.Go(func() {
+			usage, err := docker.ContainerUsage(ctx, h.dc, containerID)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed = append(failed, openapi.RouteStatsFailure{Id: containerID, Error: err.Error()})
+
+				return
+			}
+
+			total = total.Add(usage)
+			resp.Containers = append(resp.Containers, openapi.ContainerStats{
+				Id:    containerID,
+				Usage: container_stats.UsageToResponse(usage),
+			})
+		})
+	}
+
+	wg.Wait()
+
+	resp.Total = container_stats.UsageToResponse(total)
+
+	// keep the lists sorted
+	slices.SortFunc(resp.Containers, func(a, b openapi.ContainerStats) int { return strings.Compare(a.Id, b.Id) })
+
+	if len(failed) > 0 {
+		slices.SortFunc(failed, func(a, b openapi.RouteStatsFailure) int { return strings.Compare(a.Id, b.Id) })
+
+		resp.Failed = &failed
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,138 @@
+package route_stats_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/route_stats"
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+// fakeStatsReader returns the stats with the given memory usage (and the 1000 bytes limit) by the container ID.
+type fakeStatsReader map[string]uint64
+
+func (f fakeStatsReader) ContainerStats(_ context.Context, id string, _ bool) (container.StatsResponseReader, error) {
+	usage, ok := f[id]
+	if !ok {
+		return container.StatsResponseReader{}, errors.New("container is not running")
+	}
+
+	var buf bytes.Buffer
+
+	_ = json.NewEncoder(&buf).Encode(container.StatsResponse{
+		MemoryStats: container.MemoryStats{Usage: usage, Limit: 1000},
+	})
+
+	return container.StatsResponseReader{Body: io.NopCloser(&buf)}, nil
+}
+
+type fakeRouter struct {
+	routes  docker.RoutesMap
+	options map[string]docker.RouteOptions
+}
+
+func (f fakeRouter) URLToContainerByHostname(hostname string) (docker.ContainerMap, bool) {
+	v, ok := f.routes[hostname]
+
+	return v, ok
+}
+
+func (f fakeRouter) RouteOptions(id string) (docker.RouteOptions, bool) {
+	v, ok := f.options[id]
+	return v, ok
+}
+
+func TestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	var (
+		target  = url.URL{Scheme: "http", Host: "10.0.0.1:80"}
+		handler = route_stats.New(
+			fakeStatsReader{"app-1": 100, "app-2": 300, "api-1": 50, "sleeping": 1},
+			fakeRouter{
+				routes: docker.RoutesMap{
+					"app":     {"app-1": target, "app-2": target},
+					"partial": {"app-1": target, "stopping": target, "sleeping": target},
+					"file":    {"file:static": target, "api-1": target},
+					"empty":   {},
+				},
+				options: map[string]docker.RouteOptions{
+					"app-1":       {Source: docker.RouteSourceDocker},
+					"app-2":       {Source: docker.RouteSourceDocker},
+					"api-1":       {Source: docker.RouteSourceDocker},
+					"stopping":    {Source: docker.RouteSourceDocker},
+					"sleeping":    {Source: docker.RouteSourceDocker, Sleeping: true},
+					"file:static": {Source: docker.RouteSourceFile},
+				},
+			},
+		)
+		usage = func(memory int64) openapi.ResourceUsage {
+			return openapi.ResourceUsage{
+				MemoryUsageBytes: memory, MemoryLimitBytes: 1000, MemoryPercent: float64(memory) / 10,
+			}
+		}
+	)
+
+	for name, tt := range map[string]struct {
+		giveHostname string
+		wantResp     *openapi.RouteStatsResponse
+		wantErr      error
+	}{
+		"replicas": {
+			giveHostname: "app",
+			wantResp: &openapi.RouteStatsResponse{
+				Hostname: "app",
+				Total: openapi.ResourceUsage{
+					MemoryUsageBytes: 400, MemoryLimitBytes: 2000, MemoryPercent: 20,
+				},
+				Containers: []openapi.ContainerStats{{Id: "app-1", Usage: usage(100)}, {Id: "app-2", Usage: usage(300)}},
+			},
+		},
+		"failed and sleeping replicas": {
+			giveHostname: "partial",
+			wantResp: &openapi.RouteStatsResponse{
+				Hostname:   "partial",
+				Total:      usage(100),
+				Containers: []openapi.ContainerStats{{Id: "app-1", Usage: usage(100)}},
+				Failed: &[]openapi.RouteStatsFailure{
+					{Id: "stopping", Error: "failed to get the container stats: container is not running"},
+				},
+			},
+		},
+		"not a container target": {
+			giveHostname: "file",
+			wantResp: &openapi.RouteStatsResponse{
+				Hostname:   "file",
+				Total:      usage(50),
+				Containers: []openapi.ContainerStats{{Id: "api-1", Usage: usage(50)}},
+			},
+		},
+		"not found":  {giveHostname: "foo", wantErr: route_stats.ErrRouteNotFound},
+		"no targets": {giveHostname: "empty", wantErr: route_stats.ErrRouteNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := handler.Handle(context.Background(), tt.giveHostname)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantResp, resp)
+		})
+	}
+}
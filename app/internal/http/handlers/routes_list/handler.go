@@ -8,29 +8,131 @@ import (
 	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
 )
 
-type Handler struct {
-	router docker.AllContainerURLsResolver
-}
+type (
+	router interface {
+		docker.AllContainerURLsResolver
+		docker.RouteConflictsResolver
+		docker.RouteOptionsResolver
+	}
+
+	Handler struct {
+		router router
+	}
+)
 
-func New(router docker.AllContainerURLsResolver) *Handler { return &Handler{router: router} }
+func New(router router) *Handler { return &Handler{router: router} }
 
 func (h *Handler) Handle() (resp openapi.RegisteredRoutesListResponse) {
-	var routes = h.router.AllContainerURLs()
+	resp.Routes = RoutesToResponse(h.router.AllContainerURLs(), h.router)
+	resp.Conflicts = ConflictsToResponse(h.router.RouteConflicts())
 
-	resp.Routes = make([]openapi.ContainerRoute, 0, len(routes))
+	return
+}
+
+// RoutesToResponse converts the routing to the response format (sorted by the hostname).
+func RoutesToResponse(routes docker.RoutesMap, opts docker.RouteOptionsResolver) []openapi.ContainerRoute {
+	var resp = make([]openapi.ContainerRoute, 0, len(routes))
 
 	for hostname, urlsMap := range routes {
-		var route = openapi.ContainerRoute{Hostname: hostname, Urls: make(map[string]string, len(urlsMap))}
+		var route = openapi.ContainerRoute{
+			Hostname: hostname,
+			Source:   openapi.ContainerRouteSourceDocker,
+			Urls:     make(map[string]string, len(urlsMap)),
+		}
+
+		for targetID, u := range urlsMap {
+			route.Urls[targetID] = u.String()
 
-		for containerID, u := range urlsMap {
-			route.Urls[containerID] = u.String()
+			if o, ok := opts.RouteOptions(targetID); ok {
+				if o.Source != "" {
+					route.Source = openapi.ContainerRouteSource(o.Source)
+				}
+
+				if o.Autostart {
+					route.State = mergeRouteState(route.State, o)
+				}
+
+				if !o.TLS.IsZero() {
+					if route.Tls == nil {
+						route.Tls = &map[string]openapi.UpstreamTLS{}
+					}
+
+					(*route.Tls)[targetID] = upstreamTLSToResponse(o.TLS)
+				}
+			}
 		}
 
-		resp.Routes = append(resp.Routes, route)
+		resp = append(resp, route)
 	}
 
 	// keep the list sorted
-	slices.SortFunc(resp.Routes, func(a, b openapi.ContainerRoute) int { return strings.Compare(a.Hostname, b.Hostname) })
+	slices.SortFunc(resp, func(a, b openapi.ContainerRoute) int { return strings.Compare(a.Hostname, b.Hostname) })
 
-	return
+	return resp
+}
+
+// mergeRouteState returns the state of the autostart route with a few targets: the route is running, if any target
+// is running, and starting, if any target is starting.
+func mergeRouteState(current *openapi.ContainerRouteState, o docker.RouteOptions) *openapi.ContainerRouteState {
+	var state = openapi.ContainerRouteStateRunning
+
+	switch {
+	case o.Starting:
+		state = openapi.ContainerRouteStateStarting
+	case o.Sleeping:
+		state = openapi.ContainerRouteStateSleeping
+	}
+
+	var rank = map[openapi.ContainerRouteState]int{
+		openapi.ContainerRouteStateSleeping: 0,
+		openapi.ContainerRouteStateStarting: 1,
+		openapi.ContainerRouteStateRunning:  2, //nolint:mnd
+	}
+
+	if current != nil && rank[*current] >= rank[state] {
+		return current
+	}
+
+	return &state
+}
+
+// upstreamTLSToResponse converts the upstream TLS settings to the response format (the key file is not exposed).
+func upstreamTLSToResponse(t docker.UpstreamTLS) openapi.UpstreamTLS {
+	var resp = openapi.UpstreamTLS{Verify: t.Verify}
+
+	if t.CAFile != "" {
+		resp.CaFile = &t.CAFile
+	}
+
+	if t.ServerName != "" {
+		resp.ServerName = &t.ServerName
+	}
+
+	if t.CertFile != "" {
+		resp.ClientCertFile = &t.CertFile
+	}
+
+	return resp
+}
+
+// ConflictsToResponse converts the route conflicts to the response format. It returns nil if there are no conflicts
+// (the field is optional, so it's omitted).
+func ConflictsToResponse(conflicts []docker.RouteConflict) *[]openapi.RouteConflict {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	var resp = make([]openapi.RouteConflict, 0, len(conflicts))
+
+	for _, c := range conflicts {
+		resp = append(resp, openapi.RouteConflict{
+			Hostname:   c.Hostname,
+			Policy:     openapi.RouteConflictPolicy(c.Policy),
+			Containers: c.Containers,
+			RoutedTo:   c.Winners,
+			Warning:    c.String(),
+		})
+	}
+
+	return &resp
 }
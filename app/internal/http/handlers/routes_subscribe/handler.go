@@ -6,25 +6,30 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"slices"
-	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/routes_list"
 	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
 )
 
 type (
+	subscriber interface {
+		docker.RoutingUpdateSubscriber
+		docker.RouteConflictsResolver
+		docker.RouteOptionsResolver
+	}
+
 	Handler struct {
-		sub      docker.RoutingUpdateSubscriber
+		sub      subscriber
 		upgrader websocket.Upgrader
 	}
 )
 
 // New is a constructor for the [Handler] structure.
-func New(sub docker.RoutingUpdateSubscriber) *Handler { return &Handler{sub: sub} }
+func New(sub subscriber) *Handler { return &Handler{sub: sub} }
 
 // Handle is a function that handles the WebSocket connection. It reads messages from the client and sends routing
 // updates to the client in [openapi.ContainerRoutesList] format.
@@ -113,21 +118,10 @@ func (h *Handler) writer(ctx context.Context, ws *websocket.Conn) error {
 }
 
 // routesToResponse is a helper function that converts the routing data to the response format.
-func (*Handler) routesToResponse(routes map[string]map[string]url.URL) openapi.ContainerRoutesList {
-	var resp = openapi.ContainerRoutesList{Routes: make([]openapi.ContainerRoute, 0, len(routes))}
-
-	for hostname, urlsMap := range routes {
-		var route = openapi.ContainerRoute{Hostname: hostname, Urls: make(map[string]string, len(urlsMap))}
-
-		for containerID, u := range urlsMap {
-			route.Urls[containerID] = u.String()
-		}
-
-		resp.Routes = append(resp.Routes, route)
-	}
+func (h *Handler) routesToResponse(routes map[string]map[string]url.URL) openapi.ContainerRoutesList {
+	var resp = openapi.ContainerRoutesList{Routes: routes_list.RoutesToResponse(routes, h.sub)}
 
-	// keep the list sorted
-	slices.SortFunc(resp.Routes, func(a, b openapi.ContainerRoute) int { return strings.Compare(a.Hostname, b.Hostname) })
+	resp.Conflicts = routes_list.ConflictsToResponse(h.sub.RouteConflicts())
 
 	return resp
 }
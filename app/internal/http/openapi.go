@@ -7,51 +7,108 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 
 	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	containerActionHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_action"
+	containerDiagnoseHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_diagnose"
+	containerExecHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_exec"
+	containerLogsHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_logs"
+	containerStatsHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_stats"
+	containerStatsSubscribeHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/container_stats_subscribe"
 	"gh.tarampamp.am/indocker-app/app/internal/http/handlers/favicon"
 	pingHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/ping"
+	routeDeleteHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/route_delete"
+	routeSetHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/route_set"
+	routeStatsHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/route_stats"
 	routesListHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/routes_list"
 	routesSubscribeHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/routes_subscribe"
 	versionHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/version"
 	latestVersionHandler "gh.tarampamp.am/indocker-app/app/internal/http/handlers/version_latest"
 	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+	"gh.tarampamp.am/indocker-app/app/internal/routestore"
 	"gh.tarampamp.am/indocker-app/app/internal/version"
 )
 
 type (
-	dockerRouter interface {
+	// router is the routing table (it may be aggregated from several route providers).
+	router interface {
 		docker.AllContainerURLsResolver
 		docker.RoutingUpdateSubscriber
 		docker.RoutingURLResolver
+		docker.RouteConflictsResolver
+		docker.RouteOptionsResolver
+		docker.RemovedRouteResolver
+	}
+
+	// dockerState provides the docker-specific container info (and actions).
+	dockerState interface {
+		docker.RoutedContainerResolver
+		docker.ManagedContainerChecker
+		docker.StateUpdater
+		docker.ContainerDiagnoser
+		docker.ContainerWaker
+	}
+
+	// runtimeRoutes keeps the routes, added at runtime using the API.
+	runtimeRoutes interface {
+		Create(routestore.Entry) (routestore.Entry, error)
+		Put(routestore.Entry) (routestore.Entry, error)
+		Delete(hostname string) (routestore.Entry, error)
 	}
 
 	OpenAPI struct {
 		log *zap.Logger
 
 		handlers struct {
-			ping            func() openapi.PingResponse
-			version         func() openapi.AppVersionResponse
-			latestVersion   func(http.ResponseWriter) (*openapi.AppVersionResponse, error)
-			routesList      func() openapi.RegisteredRoutesListResponse
-			routesSubscribe func(http.ResponseWriter, *http.Request) error
-			favicon         func(context.Context, http.ResponseWriter, string) error
+			ping              func() openapi.PingResponse
+			version           func() openapi.AppVersionResponse
+			latestVersion     func(http.ResponseWriter) (*openapi.AppVersionResponse, error)
+			routesList        func() openapi.RegisteredRoutesListResponse
+			routesSubscribe   func(http.ResponseWriter, *http.Request) error
+			favicon           func(context.Context, http.ResponseWriter, string) error
+			containerLogs     func(http.ResponseWriter, *http.Request, string, openapi.StreamContainerLogsParams) error
+			containerStats    func(context.Context, string) (*openapi.ContainerStatsResponse, error)
+			containerStatsSub func(http.ResponseWriter, *http.Request, string) error
+			routeStats        func(context.Context, string) (*openapi.RouteStatsResponse, error)
+			containerAction   func(*http.Request, string, openapi.ContainerAction) (*openapi.ContainerActionResponse, error)
+			containerExec     func(http.ResponseWriter, *http.Request, string, openapi.ExecInContainerParams) error
+			containerDiagnose func(context.Context, string) (*openapi.ContainerDiagnosis, error)
+			routeSet          func(*http.Request, string, bool) (*openapi.RuntimeRoute, error)
+			routeDelete       func(*http.Request, string) (*openapi.RuntimeRoute, error)
 		}
 	}
 )
 
 var _ openapi.ServerInterface = (*OpenAPI)(nil) // verify interface implementation
 
-func NewOpenAPI(ctx context.Context, log *zap.Logger, dockerRouter dockerRouter) *OpenAPI {
+func NewOpenAPI(
+	ctx context.Context,
+	log *zap.Logger,
+	router router,
+	dockerState dockerState,
+	runtimeRoutes runtimeRoutes,
+	dockerClient client.ContainerAPIClient,
+	readOnlyAPI bool,
+) *OpenAPI {
 	var si = &OpenAPI{log: log}
 
 	si.handlers.ping = pingHandler.New().Handle
 	si.handlers.version = versionHandler.New(version.Version()).Handle
 	si.handlers.latestVersion = latestVersionHandler.New(func() (string, error) { return version.Latest(ctx) }).Handle
-	si.handlers.routesList = routesListHandler.New(dockerRouter).Handle
-	si.handlers.routesSubscribe = routesSubscribeHandler.New(dockerRouter).Handle
-	si.handlers.favicon = favicon.New(ctx, dockerRouter, time.Hour, 10*time.Second).Handle //nolint:mnd
+	si.handlers.routesList = routesListHandler.New(router).Handle
+	si.handlers.routesSubscribe = routesSubscribeHandler.New(router).Handle
+	si.handlers.favicon = favicon.New(ctx, router, time.Hour, 10*time.Second).Handle //nolint:mnd
+	si.handlers.containerLogs = containerLogsHandler.New(dockerClient, dockerState).Handle
+	si.handlers.containerStats = containerStatsHandler.New(dockerClient, dockerState).Handle
+	si.handlers.containerStatsSub = containerStatsSubscribeHandler.New(dockerClient, dockerState).Handle
+	si.handlers.routeStats = routeStatsHandler.New(dockerClient, router).Handle
+	si.handlers.containerAction = containerActionHandler.New(log, dockerClient, dockerState, readOnlyAPI).Handle
+	si.handlers.containerExec = containerExecHandler.New(log, dockerClient, dockerState, readOnlyAPI).Handle
+	si.handlers.containerDiagnose = containerDiagnoseHandler.New(dockerState, router).Handle
+	si.handlers.routeSet = routeSetHandler.New(log, runtimeRoutes, readOnlyAPI).Handle
+	si.handlers.routeDelete = routeDeleteHandler.New(log, runtimeRoutes, readOnlyAPI).Handle
 
 	return si
 }
@@ -95,6 +152,100 @@ func (o *OpenAPI) GetFavicon(w http.ResponseWriter, r *http.Request, hostname op
 	}
 }
 
+func (o *OpenAPI) StreamContainerLogs(
+	w http.ResponseWriter,
+	r *http.Request,
+	id openapi.ContainerIdInPath,
+	params openapi.StreamContainerLogsParams,
+) {
+	if err := o.handlers.containerLogs(w, r, id, params); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	}
+}
+
+func (o *OpenAPI) GetContainerStats(w http.ResponseWriter, r *http.Request, id openapi.ContainerIdInPath) {
+	if resp, err := o.handlers.containerStats(r.Context(), id); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	} else {
+		o.respToJson(w, resp)
+	}
+}
+
+func (o *OpenAPI) SubscribeContainerStats(
+	w http.ResponseWriter,
+	r *http.Request,
+	id openapi.ContainerIdInPath,
+	_ openapi.SubscribeContainerStatsParams,
+) {
+	if err := o.handlers.containerStatsSub(w, r, id); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	}
+}
+
+func (o *OpenAPI) GetRouteStats(w http.ResponseWriter, r *http.Request, hostname openapi.HostNameInPath) {
+	if resp, err := o.handlers.routeStats(r.Context(), hostname); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	} else {
+		o.respToJson(w, resp)
+	}
+}
+
+func (o *OpenAPI) ContainerAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	id openapi.ContainerIdInPath,
+	action openapi.ContainerAction,
+) {
+	if resp, err := o.handlers.containerAction(r, id, action); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	} else {
+		o.respToJson(w, resp)
+	}
+}
+
+func (o *OpenAPI) ExecInContainer(
+	w http.ResponseWriter,
+	r *http.Request,
+	id openapi.ContainerIdInPath,
+	params openapi.ExecInContainerParams,
+) {
+	if err := o.handlers.containerExec(w, r, id, params); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	}
+}
+
+func (o *OpenAPI) DiagnoseContainer(w http.ResponseWriter, r *http.Request, container string) {
+	if resp, err := o.handlers.containerDiagnose(r.Context(), container); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	} else {
+		o.respToJson(w, resp)
+	}
+}
+
+func (o *OpenAPI) CreateRoute(w http.ResponseWriter, r *http.Request, hostname openapi.HostNameInPath) {
+	if resp, err := o.handlers.routeSet(r, hostname, false); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	} else {
+		o.respToJson(w, resp)
+	}
+}
+
+func (o *OpenAPI) PutRoute(w http.ResponseWriter, r *http.Request, hostname openapi.HostNameInPath) {
+	if resp, err := o.handlers.routeSet(r, hostname, true); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	} else {
+		o.respToJson(w, resp)
+	}
+}
+
+func (o *OpenAPI) DeleteRoute(w http.ResponseWriter, r *http.Request, hostname openapi.HostNameInPath) {
+	if resp, err := o.handlers.routeDelete(r, hostname); err != nil {
+		o.errorToJson(w, err, o.errorToStatusCode(err))
+	} else {
+		o.respToJson(w, resp)
+	}
+}
+
 // -------------------------------------------------- Error handlers --------------------------------------------------
 
 // HandleInternalError is a default error handler for internal server errors (e.g. query parameters binding
@@ -123,6 +274,33 @@ func (o *OpenAPI) respToJson(w http.ResponseWriter, resp any) {
 	}
 }
 
+// errorToStatusCode maps the handler errors to the HTTP status codes.
+func (*OpenAPI) errorToStatusCode(err error) int {
+	switch {
+	case errors.Is(err, docker.ErrContainerNotRouted),
+		errors.Is(err, docker.ErrContainerNotFound),
+		errors.Is(err, docker.ErrContainerNotManaged),
+		errors.Is(err, routeStatsHandler.ErrRouteNotFound),
+		errors.Is(err, routestore.ErrRouteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, containerActionHandler.ErrReadOnly),
+		errors.Is(err, containerExecHandler.ErrReadOnly),
+		errors.Is(err, routeSetHandler.ErrReadOnly),
+		errors.Is(err, routeDeleteHandler.ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, containerActionHandler.ErrUnknownAction),
+		errors.Is(err, containerLogsHandler.ErrNoStreams),
+		errors.Is(err, routestore.ErrInvalidRoute):
+		return http.StatusBadRequest
+	case errors.Is(err, routestore.ErrRouteExists):
+		return http.StatusConflict
+	case errors.Is(err, routeSetHandler.ErrUnsupportedContentType):
+		return http.StatusUnsupportedMediaType
+	}
+
+	return http.StatusInternalServerError
+}
+
 func (o *OpenAPI) errorToJson(w http.ResponseWriter, err error, status int) {
 	w.Header().Set(contentTypeHeader, contentTypeJSON)
 	w.WriteHeader(status)
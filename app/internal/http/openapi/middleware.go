@@ -1,6 +1,11 @@
 package openapi
 
-import "net/http"
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
 
 // CorsMiddleware is a middleware that adds the CORS headers to the response.
 func CorsMiddleware() MiddlewareFunc {
@@ -14,3 +19,38 @@ func CorsMiddleware() MiddlewareFunc {
 		})
 	}
 }
+
+// SameOriginMiddleware is a middleware that rejects the requests, which change something (all methods except GET,
+// HEAD, and OPTIONS), sent by the browser from a page of another origin. Otherwise, any web page the user visits
+// could, for example, stop the containers (the browsers send such requests without the CORS preflight). Requests
+// without the Origin header (e.g. made by curl) are allowed.
+func SameOriginMiddleware() MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				if origin := r.Header.Get("Origin"); origin != "" && !isSameOrigin(origin, r.Host) {
+					w.Header().Set("Content-Type", "application/json; charset=utf-8")
+					w.WriteHeader(http.StatusForbidden)
+
+					_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "cross-origin requests are not allowed"})
+
+					return
+				}
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isSameOrigin checks whether the origin (e.g. "https://monitor.indocker.app") points to the requested host.
+func isSameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Host != "" && strings.EqualFold(u.Host, host)
+}
@@ -0,0 +1,60 @@
+package openapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+)
+
+func TestSameOriginMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var handler = openapi.SameOriginMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for name, tt := range map[string]struct {
+		giveMethod string
+		giveOrigin string
+		wantCode   int
+	}{
+		"no origin": {giveMethod: http.MethodPost, wantCode: http.StatusTeapot},
+		"same origin": {
+			giveMethod: http.MethodPost, giveOrigin: "https://Monitor.indocker.app", wantCode: http.StatusTeapot,
+		},
+		"foreign origin": {
+			giveMethod: http.MethodPost, giveOrigin: "https://evil.example", wantCode: http.StatusForbidden,
+		},
+		"foreign origin, delete": {
+			giveMethod: http.MethodDelete, giveOrigin: "https://evil.example", wantCode: http.StatusForbidden,
+		},
+		"another port": {
+			giveMethod: http.MethodPut, giveOrigin: "https://monitor.indocker.app:8443", wantCode: http.StatusForbidden,
+		},
+		"null origin": {giveMethod: http.MethodPost, giveOrigin: "null", wantCode: http.StatusForbidden},
+		"foreign origin, read-only": {
+			giveMethod: http.MethodGet, giveOrigin: "https://evil.example", wantCode: http.StatusTeapot,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				req = httptest.NewRequest(tt.giveMethod, "https://monitor.indocker.app/api/containers/foo/stop", nil)
+				rec = httptest.NewRecorder()
+			)
+
+			if tt.giveOrigin != "" {
+				req.Header.Set("Origin", tt.giveOrigin)
+			}
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
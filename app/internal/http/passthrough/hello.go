@@ -0,0 +1,56 @@
+package passthrough
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// errHelloRead aborts the handshake, once the ClientHello is read.
+var errHelloRead = errors.New("client hello read")
+
+// readClientHello reads the TLS ClientHello from the reader, and returns the requested server name (SNI, lowercased,
+// may be empty) and all the bytes read. The standard TLS server is used to parse the message, and the handshake is
+// aborted right after the ClientHello is parsed, so nothing is written back.
+func readClientHello(r io.Reader) (serverName string, peeked []byte, _ error) {
+	var (
+		buf   bytes.Buffer
+		hello *tls.ClientHelloInfo
+	)
+
+	err := tls.Server(readOnlyConn{reader: io.TeeReader(r, &buf)}, &tls.Config{ //nolint:gosec
+		GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+			hello = info
+
+			return nil, errHelloRead
+		},
+	}).Handshake()
+
+	if hello == nil {
+		if err == nil {
+			err = errors.New("no client hello")
+		}
+
+		return "", buf.Bytes(), err
+	}
+
+	return strings.ToLower(hello.ServerName), buf.Bytes(), nil
+}
+
+// readOnlyConn is the connection, which can only be read from.
+type readOnlyConn struct{ reader io.Reader }
+
+var _ net.Conn = readOnlyConn{} // verify interface implementation
+
+func (c readOnlyConn) Read(p []byte) (int, error)     { return c.reader.Read(p) }
+func (readOnlyConn) Write([]byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (readOnlyConn) Close() error                     { return nil }
+func (readOnlyConn) LocalAddr() net.Addr              { return nil }
+func (readOnlyConn) RemoteAddr() net.Addr             { return nil }
+func (readOnlyConn) SetDeadline(time.Time) error      { return nil }
+func (readOnlyConn) SetReadDeadline(time.Time) error  { return nil }
+func (readOnlyConn) SetWriteDeadline(time.Time) error { return nil }
@@ -0,0 +1,329 @@
+// Package passthrough implements the SNI-based TLS passthrough: the raw TLS connections to the hostnames, routed to
+// the containers with the TLS passthrough label, are passed to the containers as is (the containers terminate TLS
+// themselves), while the rest of the connections are served by the HTTPS server as usual.
+package passthrough
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type (
+	router interface {
+		docker.RoutingURLResolver
+		docker.RouteOptionsResolver
+	}
+
+	// watchedRouter is the router, which reports the routing updates (so the listener knows, whether any route uses
+	// the TLS passthrough).
+	watchedRouter interface {
+		router
+		docker.AllContainerURLsResolver
+		docker.RoutingUpdateSubscriber
+	}
+
+	// Listener wraps the HTTPS server listener. It peeks at the TLS ClientHello of every accepted connection, and
+	// splices the connection to the upstream, if the requested hostname (SNI) is routed to the container with the
+	// TLS passthrough enabled. Other connections are returned by the Accept method (with the peeked bytes). While no
+	// route uses the TLS passthrough, the connections are returned as is (without peeking).
+	Listener struct {
+		net.Listener
+
+		log     *zap.Logger
+		router  watchedRouter
+		enabled atomic.Bool // whether any route uses the TLS passthrough
+
+		startOnce sync.Once
+		accepted  chan accepted // the connections (or errors) to return from the Accept method
+		done      chan struct{} // closed when the listener is closed
+		closeOnce sync.Once
+
+		splicedMu sync.Mutex
+		spliced   map[net.Conn]struct{} // the active passthrough connections (both sides)
+	}
+
+	// accepted is the result of the underlying listener Accept call.
+	accepted struct {
+		conn net.Conn
+		err  error
+	}
+)
+
+var _ net.Listener = (*Listener)(nil) // verify interface implementation
+
+const (
+	helloTimeout = 10 * time.Second // maximum amount of time to wait for the TLS ClientHello
+	dialTimeout  = 5 * time.Second  // maximum amount of time to connect to the upstream
+)
+
+// New wraps the listener. The router is used to find the TLS passthrough upstream by the hostname.
+func New(ln net.Listener, log *zap.Logger, router watchedRouter) *Listener {
+	return &Listener{
+		Listener: ln,
+		log:      log,
+		router:   router,
+		accepted: make(chan accepted),
+		done:     make(chan struct{}),
+		spliced:  make(map[net.Conn]struct{}),
+	}
+}
+
+// Accept waits for and returns the next connection, which is not passed through to the upstream.
+func (l *Listener) Accept() (net.Conn, error) {
+	l.startOnce.Do(func() {
+		var sub, stop = l.router.SubscribeForRoutingUpdates() // subscribe first, to not miss the updates
+
+		l.refresh(l.router.AllContainerURLs())
+
+		go l.watchRoutes(sub, stop)
+		go l.acceptLoop()
+	})
+
+	select {
+	case a := <-l.accepted:
+		return a.conn, a.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes the listener, and the active passthrough connections.
+func (l *Listener) Close() error {
+	var err error
+
+	l.closeOnce.Do(func() {
+		close(l.done)
+
+		err = l.Listener.Close()
+
+		l.splicedMu.Lock()
+		for conn := range l.spliced {
+			_ = conn.Close()
+		}
+		l.splicedMu.Unlock()
+	})
+
+	return err
+}
+
+// acceptLoop accepts the connections from the underlying listener, and handles them in the background (the slow
+// clients must not block the other ones).
+func (l *Listener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.accepted <- accepted{err: err}:
+			case <-l.done:
+				return
+			}
+
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue // the server decides, whether the error is temporary
+		}
+
+		if !l.enabled.Load() {
+			select { // no passthrough routes - the server handles the connection as is
+			case l.accepted <- accepted{conn: conn}:
+			case <-l.done:
+				_ = conn.Close()
+
+				return
+			}
+
+			continue
+		}
+
+		go l.handle(conn)
+	}
+}
+
+// watchRoutes keeps the enabled flag up to date, until the listener is closed.
+func (l *Listener) watchRoutes(sub <-chan docker.RoutesMap, stop func()) {
+	defer stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case routes, ok := <-sub:
+			if !ok {
+				return
+			}
+
+			l.refresh(routes)
+		}
+	}
+}
+
+// refresh enables the ClientHello peeking, if any route uses the TLS passthrough.
+func (l *Listener) refresh(routes docker.RoutesMap) {
+	for _, targets := range routes {
+		for id := range targets {
+			if opts, ok := l.router.RouteOptions(id); ok && opts.Passthrough {
+				l.enabled.Store(true)
+
+				return
+			}
+		}
+	}
+
+	l.enabled.Store(false)
+}
+
+// handle peeks at the ClientHello, and splices the connection to the passthrough upstream, or returns it to the
+// server.
+func (l *Listener) handle(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(helloTimeout))
+
+	serverName, peeked, helloErr := readClientHello(conn)
+
+	_ = conn.SetReadDeadline(time.Time{})
+
+	if helloErr == nil {
+		if addr, ok := Upstream(l.router, serverName); ok {
+			l.splice(conn, serverName, addr, peeked)
+
+			return
+		}
+	}
+
+	// not a TLS connection, or not a passthrough hostname - the server handles it (including the errors)
+	var wrapped = &peekedConn{Conn: conn, reader: io.MultiReader(bytes.NewReader(peeked), conn)}
+
+	select {
+	case l.accepted <- accepted{conn: wrapped}:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+// Upstream returns the address of the TLS passthrough upstream for the hostname (e.g. "vault.indocker.app"), if the
+// hostname is routed to the container with the TLS passthrough enabled.
+func Upstream(router router, hostname string) (string, bool) {
+	if hostname == "" {
+		return "", false
+	}
+
+	targets, found := router.URLToContainerByHostname(hostname)
+	if !found {
+		return "", false
+	}
+
+	// the passthrough connection can't be balanced by the path, so the first passthrough target is used
+	for _, id := range slices.Sorted(maps.Keys(targets)) {
+		if opts, ok := router.RouteOptions(id); ok && opts.Passthrough {
+			var u = targets[id]
+
+			return u.Host, true
+		}
+	}
+
+	return "", false
+}
+
+// splice connects the client to the upstream, replays the peeked bytes, and copies the data in both directions
+// until both sides are done.
+func (l *Listener) splice(client net.Conn, serverName, addr string, peeked []byte) {
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	upstream, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		l.log.Warn("Failed to connect to the TLS passthrough upstream",
+			zap.String("server name", serverName),
+			zap.String("upstream", addr),
+			zap.Error(err),
+		)
+
+		return
+	}
+
+	defer func() { _ = upstream.Close() }()
+
+	if !l.track(client, upstream) {
+		return // the listener is closed
+	}
+
+	defer l.untrack(client, upstream)
+
+	l.log.Debug("TLS passthrough connection",
+		zap.String("server name", serverName),
+		zap.String("client", client.RemoteAddr().String()),
+		zap.String("upstream", addr),
+	)
+
+	if _, err = upstream.Write(peeked); err != nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() { _, _ = io.Copy(upstream, client); closeWrite(upstream) })
+	wg.Go(func() { _, _ = io.Copy(client, upstream); closeWrite(client) })
+
+	wg.Wait()
+}
+
+// track starts tracking the passthrough connections. It returns false if the listener is closed.
+func (l *Listener) track(conns ...net.Conn) bool {
+	l.splicedMu.Lock()
+	defer l.splicedMu.Unlock()
+
+	select {
+	case <-l.done:
+		return false
+	default:
+	}
+
+	for _, conn := range conns {
+		l.spliced[conn] = struct{}{}
+	}
+
+	return true
+}
+
+// untrack stops tracking the passthrough connections.
+func (l *Listener) untrack(conns ...net.Conn) {
+	l.splicedMu.Lock()
+	defer l.splicedMu.Unlock()
+
+	for _, conn := range conns {
+		delete(l.spliced, conn)
+	}
+}
+
+// closeWrite signals the peer that no more data will be sent (half-close), if the connection supports it.
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	} else {
+		_ = conn.Close()
+	}
+}
+
+// peekedConn replays the peeked bytes before reading from the connection.
+type peekedConn struct {
+	net.Conn
+
+	reader io.Reader
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) { return c.reader.Read(p) }
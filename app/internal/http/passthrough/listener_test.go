@@ -0,0 +1,202 @@
+package passthrough_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/passthrough"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestListener(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// the upstream terminates TLS itself (with its own certificate)
+	var upstream = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "upstream: "+r.Host)
+	}))
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	var (
+		mem    = routing.NewMemoryProvider("test")
+		router = routing.NewAggregator(zap.NewNop()).Add(mem, 0)
+	)
+
+	mem.Set(routing.Route{
+		Hostname: "vault",
+		TargetID: "test:vault",
+		URL:      *upstreamURL,
+		Options:  docker.RouteOptions{Passthrough: true},
+	})
+	mem.Set(routing.Route{Hostname: "app", TargetID: "test:app", URL: *upstreamURL})
+
+	go func() { _ = router.Run(ctx) }()
+
+	<-router.Ready()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	// the server terminates TLS for the rest of the hostnames
+	var server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "server: "+r.Host)
+	}))
+
+	server.Listener = passthrough.New(ln, zap.NewNop(), router)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	// get requests the URL using the server, and returns the response body and the server certificate
+	var get = func(t *testing.T, rawURL string) (string, *tls.Certificate) {
+		t.Helper()
+
+		var client = http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, ln.Addr().String())
+				},
+			},
+		}
+
+		resp, reqErr := client.Get(rawURL)
+		require.NoError(t, reqErr)
+
+		defer func() { _ = resp.Body.Close() }()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		if resp.TLS == nil {
+			return string(body), nil
+		}
+
+		return string(body), &tls.Certificate{Certificate: [][]byte{resp.TLS.PeerCertificates[0].Raw}}
+	}
+
+	t.Run("passthrough", func(t *testing.T) {
+		t.Parallel()
+
+		body, cert := get(t, "https://vault.indocker.app/")
+
+		assert.Equal(t, "upstream: vault.indocker.app", body)
+		assert.Equal(t, upstream.TLS.Certificates[0].Certificate[0], cert.Certificate[0])
+	})
+
+	t.Run("terminated", func(t *testing.T) {
+		t.Parallel()
+
+		body, cert := get(t, "https://app.indocker.app/")
+
+		assert.Equal(t, "server: app.indocker.app", body)
+		assert.Equal(t, server.TLS.Certificates[0].Certificate[0], cert.Certificate[0])
+	})
+
+	t.Run("not tls", func(t *testing.T) {
+		t.Parallel()
+
+		// the peeked bytes are replayed, so the server sees the plain HTTP request
+		body, _ := get(t, "http://vault.indocker.app/")
+
+		assert.Contains(t, body, "Client sent an HTTP request to an HTTPS server")
+	})
+}
+
+func TestListener_NoPassthroughRoutes(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var (
+		mem    = routing.NewMemoryProvider("test")
+		router = routing.NewAggregator(zap.NewNop()).Add(mem, 0)
+	)
+
+	mem.Set(routing.Route{Hostname: "app", TargetID: "test:app", URL: url.URL{Scheme: "http", Host: "127.0.0.1:1"}})
+
+	go func() { _ = router.Run(ctx) }()
+
+	<-router.Ready()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		listener = passthrough.New(ln, zap.NewNop(), router)
+		conns    = make(chan net.Conn)
+	)
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			conns <- conn
+		}
+	}()
+
+	// accept dials the listener, sends the data (if any), and returns the accepted connection (or nil on timeout)
+	var accept = func(t *testing.T, data string) net.Conn {
+		t.Helper()
+
+		client, dialErr := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, dialErr)
+
+		t.Cleanup(func() { _ = client.Close() })
+
+		if data != "" {
+			_, _ = io.WriteString(client, data)
+		}
+
+		select {
+		case conn := <-conns:
+			t.Cleanup(func() { _ = conn.Close() })
+
+			return conn
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	// the connection is returned as is, without waiting for the ClientHello (the client sends nothing)
+	assert.IsType(t, &net.TCPConn{}, accept(t, ""))
+
+	// once the passthrough route is added, the ClientHello is peeked
+	mem.Set(routing.Route{
+		Hostname: "vault",
+		TargetID: "test:vault",
+		URL:      url.URL{Scheme: "https", Host: "127.0.0.1:1"},
+		Options:  docker.RouteOptions{Passthrough: true},
+	})
+
+	assert.Eventually(t, func() bool {
+		var conn = accept(t, "GET / HTTP/1.1\r\n\r\n") // not TLS, so the peeked bytes are replayed
+
+		_, isRaw := conn.(*net.TCPConn)
+
+		return conn != nil && !isRaw
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,140 @@
+package proxy_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+// fakeWaker "starts" the container by publishing its running route with a delay, like the docker state does (the
+// router gets the changes asynchronously).
+type fakeWaker struct {
+	mem     *routing.MemoryProvider
+	running routing.Route
+	err     error
+
+	mu    sync.Mutex
+	woken []string
+}
+
+func (f *fakeWaker) Wake(_ context.Context, containerID string) error {
+	f.mu.Lock()
+	f.woken = append(f.woken, containerID)
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+
+		f.mem.Set(f.running)
+	}()
+
+	return nil
+}
+
+func (*fakeWaker) Touch(string) {}
+
+func (f *fakeWaker) wokenIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.woken...)
+}
+
+func TestHandler_Autostart(t *testing.T) {
+	t.Parallel()
+
+	var upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "awake")
+	}))
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	for name, tt := range map[string]struct {
+		giveAccept string
+		giveErr    error
+		wantCode   int
+		wantBody   string
+	}{
+		"api client waits for the container": {
+			wantCode: http.StatusOK,
+			wantBody: "awake",
+		},
+		"browser gets the starting page": {
+			giveAccept: "text/html,application/xhtml+xml",
+			wantCode:   http.StatusServiceUnavailable,
+			wantBody:   "Starting app.indocker.app...",
+		},
+		"failed to start": {
+			giveErr:  errors.New("no such image"),
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: "no such image",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				mem    = routing.NewMemoryProvider("test")
+				router = routing.NewAggregator(zap.NewNop()).Add(mem, 0)
+				waker  = &fakeWaker{mem: mem, err: tt.giveErr, running: routing.Route{
+					Hostname: "app",
+					TargetID: "app-id",
+					URL:      *upstreamURL,
+					Options:  docker.RouteOptions{Autostart: true},
+				}}
+			)
+
+			mem.Set(routing.Route{
+				Hostname: "app",
+				TargetID: "app-id",
+				URL:      url.URL{Scheme: "http", Host: "app-1:80"}, // the stopped container has no IP address
+				Options:  docker.RouteOptions{Autostart: true, Sleeping: true},
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			go func() { _ = router.Run(ctx) }()
+
+			<-router.Ready()
+
+			var (
+				req = httptest.NewRequest(http.MethodGet, "http://app.indocker.app/", nil)
+				rec = httptest.NewRecorder()
+			)
+
+			if tt.giveAccept != "" {
+				req.Header.Set("Accept", tt.giveAccept)
+			}
+
+			proxy.New(zap.NewNop(), router, waker, "v0.0.0").ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+
+			assert.EventuallyWithT(t, func(c *assert.CollectT) { // the browser case wakes in the background
+				assert.Equal(c, []string{"app-id"}, waker.wokenIDs())
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package proxy_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	testgrpc "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
+)
+
+// echoService echoes the payloads back, and sets the trailer on every call.
+type echoService struct {
+	testgrpc.UnimplementedTestServiceServer
+}
+
+func (echoService) UnaryCall(ctx context.Context, req *testgrpc.SimpleRequest) (*testgrpc.SimpleResponse, error) {
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("x-call", "unary"))
+
+	if len(req.GetPayload().GetBody()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty payload")
+	}
+
+	return &testgrpc.SimpleResponse{Payload: req.GetPayload()}, nil
+}
+
+func (echoService) FullDuplexCall(
+	stream grpc.BidiStreamingServer[testgrpc.StreamingOutputCallRequest, testgrpc.StreamingOutputCallResponse],
+) error {
+	stream.SetTrailer(metadata.Pairs("x-call", "duplex"))
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err = stream.Send(&testgrpc.StreamingOutputCallResponse{Payload: req.GetPayload()}); err != nil {
+			return err
+		}
+	}
+}
+
+// startGRPCServer starts the gRPC server with the echo service, and returns its address.
+func startGRPCServer(t *testing.T, opts ...grpc.ServerOption) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var srv = grpc.NewServer(opts...)
+
+	testgrpc.RegisterTestServiceServer(srv, echoService{})
+
+	go func() { _ = srv.Serve(ln) }()
+
+	t.Cleanup(srv.Stop)
+
+	return ln.Addr().String()
+}
+
+func TestHandler_GRPC(t *testing.T) {
+	t.Parallel()
+
+	// borrow the self-signed certificate from the test TLS server
+	var tlsSrv = httptest.NewTLSServer(http.NotFoundHandler())
+	var cert = tlsSrv.TLS.Certificates[0]
+
+	tlsSrv.Close()
+
+	var router = newRouter(t, map[string]url.URL{
+		"grpc":  {Scheme: "grpc", Host: startGRPCServer(t)},
+		"grpcs": {Scheme: "grpcs", Host: startGRPCServer(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))},
+	})
+
+	// the clients use HTTP/2 over TLS (negotiated using ALPN), like the real HTTPS server does
+	var proxySrv = httptest.NewUnstartedServer(proxy.New(zap.NewNop(), router, nil, "v0.0.0"))
+
+	proxySrv.EnableHTTP2 = true
+	proxySrv.StartTLS()
+	t.Cleanup(proxySrv.Close)
+
+	for _, upstream := range []string{"grpc", "grpcs"} {
+		t.Run(upstream, func(t *testing.T) {
+			t.Parallel()
+
+			conn, err := grpc.NewClient(proxySrv.Listener.Addr().String(),
+				grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})), //nolint:gosec
+				grpc.WithAuthority(upstream+".indocker.app"),
+			)
+			require.NoError(t, err)
+
+			t.Cleanup(func() { _ = conn.Close() })
+
+			var client = testgrpc.NewTestServiceClient(conn)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			t.Run("unary", func(t *testing.T) {
+				var trailer metadata.MD
+
+				resp, callErr := client.UnaryCall(ctx,
+					&testgrpc.SimpleRequest{Payload: &testgrpc.Payload{Body: []byte("ping")}},
+					grpc.Trailer(&trailer),
+				)
+				require.NoError(t, callErr)
+				assert.Equal(t, []byte("ping"), resp.GetPayload().GetBody())
+				assert.Equal(t, []string{"unary"}, trailer.Get("x-call"))
+
+				// the status is sent using the trailers, too
+				_, callErr = client.UnaryCall(ctx, &testgrpc.SimpleRequest{})
+				assert.Equal(t, codes.InvalidArgument, status.Code(callErr))
+			})
+
+			t.Run("bidirectional streaming", func(t *testing.T) {
+				stream, callErr := client.FullDuplexCall(ctx)
+				require.NoError(t, callErr)
+
+				// every message is answered before the next one is sent, so the streaming works end to end
+				for _, msg := range []string{"one", "two", "three"} {
+					require.NoError(t, stream.Send(&testgrpc.StreamingOutputCallRequest{
+						Payload: &testgrpc.Payload{Body: []byte(msg)},
+					}))
+
+					resp, recvErr := stream.Recv()
+					require.NoError(t, recvErr)
+					assert.Equal(t, []byte(msg), resp.GetPayload().GetBody())
+				}
+
+				require.NoError(t, stream.CloseSend())
+
+				_, callErr = stream.Recv()
+				assert.ErrorIs(t, callErr, io.EOF)
+				assert.Equal(t, []string{"duplex"}, stream.Trailer().Get("x-call"))
+			})
+		})
+	}
+}
@@ -1,17 +1,25 @@
 package proxy
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -22,19 +30,58 @@ type (
 	dockerRouter interface {
 		docker.RoutingURLResolver
 		docker.AllContainerURLsResolver
+		docker.RouteConflictsResolver
+		docker.RouteOptionsResolver
+		docker.RoutingUpdateSubscriber
+		docker.RemovedRouteResolver
 	}
 
 	Handler struct {
-		router     dockerRouter
-		log        *zap.Logger
-		appVersion string
+		router      dockerRouter
+		waker       docker.ContainerWaker
+		log         *zap.Logger
+		appVersion  string
+		gracePeriod time.Duration // how long to hold the requests to the recently removed routes
+
+		errorTemplates ErrorTemplates // user-defined error page templates (optional)
+		hideHosts      bool           // do not list the registered hosts on the error pages
+
+		streams    streams             // the proxied requests, tracked to close the long-lived ones on shutdown
+		transports *upstreamTransports // the upstream transports (by the upstream TLS settings)
 	}
+
+	// Option allows to configure the proxy handler.
+	Option func(*Handler)
 )
 
 var _ http.Handler = (*Handler)(nil) // verify interface implementation
 
-func New(log *zap.Logger, router dockerRouter, appVersion string) *Handler {
-	return &Handler{log: log, router: router, appVersion: appVersion}
+// WithGracePeriod sets the amount of time, during which the requests to the recently removed route (e.g. the
+// container is being recreated) are held until the route comes back. Zero disables the holding.
+func WithGracePeriod(d time.Duration) Option { return func(h *Handler) { h.gracePeriod = d } }
+
+// WithErrorTemplates sets the user-defined error page templates, which take precedence over the embedded one.
+func WithErrorTemplates(t ErrorTemplates) Option { return func(h *Handler) { h.errorTemplates = t } }
+
+// WithHiddenHosts hides the list of the registered hosts on the error pages (the "did you mean" suggestions are
+// still shown).
+func WithHiddenHosts(hide bool) Option { return func(h *Handler) { h.hideHosts = hide } }
+
+// New creates the proxy handler. The waker is used to start the stopped containers with the autostart label.
+func New(
+	log *zap.Logger,
+	router dockerRouter,
+	waker docker.ContainerWaker,
+	appVersion string,
+	opts ...Option,
+) *Handler {
+	var h = Handler{log: log, router: router, waker: waker, appVersion: appVersion, transports: newUpstreamTransports()}
+
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	return &h
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -50,29 +97,113 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if urls, found := h.router.URLToContainerByHostname(host); found && len(urls) > 0 {
-		var u url.URL
+	var urls, found = h.router.URLToContainerByHostname(host)
+
+	if !found || len(urls) == 0 {
+		var written bool
 
-		// pick a random url in round-robin fashion
-		for _, u = range urls {
-			break
+		if urls, found, written = h.holdUntilRouted(w, r, host); written {
+			return
 		}
+	}
+
+	if found && len(urls) > 0 {
+		targetID, u, opts, matched := h.pickTarget(urls, r.URL.Path)
+		if !matched {
+			h.renderErrorNice(w, r, host, http.StatusNotFound, fmt.Errorf("no route for the path %s", r.URL.Path))
+
+			return
+		}
+
+		if opts.Autostart {
+			if opts.Sleeping || opts.Starting {
+				if !h.wakeUp(w, r, host, targetID) {
+					return
+				}
+
+				// the upstream address is known only once the container is started
+				if u, opts, matched = h.awakeTarget(r, host, targetID); !matched {
+					h.renderErrorNice(w, r, host, http.StatusServiceUnavailable, errors.New("container is not started"))
+
+					return
+				}
+			}
+
+			h.waker.Touch(targetID)
+			defer h.waker.Touch(targetID) // the request may take a while (e.g. websockets)
+		}
+
+		ctx, st, done := h.streams.track(r.Context())
+		defer done()
+
+		r = r.WithContext(ctx) // canceling the context closes the upstream connection (including the upgraded ones)
+
+		var tlsSettings docker.UpstreamTLS // the defaults, unless the upstream uses TLS
+
+		if docker.IsTLSScheme(u.Scheme) {
+			tlsSettings = opts.TLS
+		}
+
+		upstreamTransports, transportsErr := h.transports.get(tlsSettings)
+		if transportsErr != nil {
+			h.log.Warn("Failed to apply the upstream TLS settings", zap.String("host", host), zap.Error(transportsErr))
+			h.renderUpstreamError(w, r, host, u, transportsErr)
+
+			return
+		}
+
+		var transport, scheme = upstreamTransports.forScheme(u.Scheme)
 
 		(&httputil.ReverseProxy{
 			Director: func(pr *http.Request) {
 				var clone = r.Clone(r.Context())
 
-				clone.URL.Scheme = u.Scheme // set target scheme
-				clone.URL.Host = u.Host     // set target host
-				clone.Host = u.Host         // --//--
+				clone.URL.Scheme = scheme // set target scheme
+				clone.URL.Host = u.Host   // set target host
+
+				if !opts.PreserveHost {
+					clone.Host = u.Host // --//--
+				}
+
+				for name, value := range opts.Headers {
+					clone.Header.Set(name, value)
+				}
+
+				for _, prefix := range opts.StripPrefixes {
+					if matchPathPrefix(clone.URL.Path, prefix) {
+						clone.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(clone.URL.Path, prefix), "/")
+						clone.URL.RawPath = "" // re-encoded from the path
+						clone.Header.Set("X-Forwarded-Prefix", prefix)
+
+						break
+					}
+				}
 
 				*pr = *clone // swap the request
 			},
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, //nolint:gosec
-			ErrorLog:  zap.NewStdLog(h.log),
+			Transport: transport,
+			// flush immediately, so the SSE events and streamed chunks are not delayed
+			FlushInterval: -1,
+			ErrorLog:      zap.NewStdLog(h.log),
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				h.renderUpstreamError(w, r, host, u, err)
+			},
 			ModifyResponse: func(resp *http.Response) error {
 				resp.Header.Set("X-Indocker-Downstream-Url", u.String())
 
+				if isLongLivedResponse(resp) {
+					h.streams.markLongLived(st)
+				}
+
+				// the streams may last longer than the server timeouts allow, so lift them (errors are ignored,
+				// since not every writer supports it; the hijacked connections have no deadlines anyway)
+				if isStreamingResponse(resp) {
+					var rc = http.NewResponseController(w)
+
+					_ = rc.SetReadDeadline(time.Time{})
+					_ = rc.SetWriteDeadline(time.Time{})
+				}
+
 				return nil
 			},
 		}).ServeHTTP(w, r)
@@ -80,7 +211,156 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.renderErrorNice(w, host, http.StatusNotFound, errors.New("container not found"))
+	h.renderErrorNice(w, r, host, http.StatusNotFound, errors.New("container not found"))
+}
+
+// holdUntilRouted holds the request to the recently removed route (e.g. the container is being recreated) until the
+// route comes back, or the grace period ends. Websocket and SSE clients get the 503 "retry later" response
+// immediately, since they are expected to reconnect. It returns the route URLs, if the route is back, and true as
+// the last value, if the response is already written.
+func (h *Handler) holdUntilRouted(
+	w http.ResponseWriter, r *http.Request, host string,
+) (_ map[string]url.URL, found, written bool) {
+	if h.gracePeriod <= 0 {
+		return nil, false, false
+	}
+
+	removedAt, removed := h.router.RouteRemovedAt(host)
+	if !removed {
+		return nil, false, false
+	}
+
+	var left = h.gracePeriod - time.Since(removedAt)
+	if left <= 0 {
+		return nil, false, false
+	}
+
+	if isStreamingRequest(r) {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(left.Round(time.Second).Seconds()))))
+		h.renderErrorNice(w, r, host, http.StatusServiceUnavailable, errors.New("container is restarting, retry later"))
+
+		return nil, false, true
+	}
+
+	sub, stop := h.router.SubscribeForRoutingUpdates()
+	defer stop()
+
+	// the route may be back between the first lookup and the subscription
+	if urls, ok := h.router.URLToContainerByHostname(host); ok && len(urls) > 0 {
+		return urls, true, false
+	}
+
+	var timer = time.NewTimer(left)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-sub:
+			if urls, ok := h.router.URLToContainerByHostname(host); ok && len(urls) > 0 {
+				return urls, true, false
+			}
+		case <-timer.C:
+			return nil, false, false
+		case <-r.Context().Done():
+			return nil, false, true // the client is gone, nothing to respond
+		}
+	}
+}
+
+// isStreamingRequest returns true if the request is a websocket upgrade or SSE (server-sent events) subscription.
+func isStreamingRequest(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// wakeUp starts the stopped container with the autostart label. Browsers get the "starting..." page immediately
+// (it reloads itself until the container is ready), while other clients wait for the container readiness. It
+// returns false if the response is already written.
+func (h *Handler) wakeUp(w http.ResponseWriter, r *http.Request, host, targetID string) bool {
+	if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		go func() { _ = h.waker.Wake(context.WithoutCancel(r.Context()), targetID) }()
+
+		h.renderStarting(w, host)
+
+		return false
+	}
+
+	if err := h.waker.Wake(r.Context(), targetID); err != nil {
+		h.renderErrorNice(w, r, host, http.StatusServiceUnavailable, fmt.Errorf("failed to start the container: %w", err))
+
+		return false
+	}
+
+	return true
+}
+
+// awakeRouteTimeout is the maximum amount of time to wait for the router to publish the started container.
+const awakeRouteTimeout = 5 * time.Second
+
+// awakeTarget returns the upstream URL and options of the started (woken) container. The router gets the docker
+// state changes asynchronously, so the route target may still be "sleeping" right after the container is started -
+// in this case, the routing updates are awaited (for a while).
+func (h *Handler) awakeTarget(r *http.Request, host, targetID string) (url.URL, docker.RouteOptions, bool) {
+	sub, stop := h.router.SubscribeForRoutingUpdates()
+	defer stop()
+
+	var timer = time.NewTimer(awakeRouteTimeout)
+	defer timer.Stop()
+
+	for {
+		if urls, found := h.router.URLToContainerByHostname(host); found {
+			if u, routed := urls[targetID]; routed {
+				if opts, ok := h.router.RouteOptions(targetID); ok && !opts.Sleeping {
+					return u, opts, true
+				}
+			}
+		}
+
+		select {
+		case <-sub:
+		case <-timer.C:
+			return url.URL{}, docker.RouteOptions{}, false
+		case <-r.Context().Done():
+			return url.URL{}, docker.RouteOptions{}, false
+		}
+	}
+}
+
+// pickTarget picks the route target for the request path. Targets with the longest matching path prefix win, and
+// the one among them is picked randomly (map iteration order). Targets without the path prefix match any path.
+func (h *Handler) pickTarget(urls map[string]url.URL, path string) (string, url.URL, docker.RouteOptions, bool) {
+	var (
+		bestID   string
+		bestURL  url.URL
+		bestOpts docker.RouteOptions
+		bestLen  = -1
+	)
+
+	for targetID, u := range urls {
+		var opts, _ = h.router.RouteOptions(targetID)
+
+		if !matchPathPrefix(path, opts.PathPrefix) || len(opts.PathPrefix) <= bestLen {
+			continue
+		}
+
+		bestID, bestURL, bestOpts, bestLen = targetID, u, opts, len(opts.PathPrefix)
+	}
+
+	return bestID, bestURL, bestOpts, bestLen >= 0
+}
+
+// matchPathPrefix reports whether the path is the prefix itself or is "below" it ("/app" matches "/app" and
+// "/app/foo", but not "/apple").
+func matchPathPrefix(path, prefix string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
 }
 
 var (
@@ -90,6 +370,8 @@ var (
 	err5xxSvg string
 	//go:embed 4xx.svg
 	err4xxSvg string
+	//go:embed starting.tpl.html
+	startingTplHtml string
 )
 
 var errorTemplate = func() *template.Template { //nolint:gochecknoglobals
@@ -101,18 +383,122 @@ var errorTemplate = func() *template.Template { //nolint:gochecknoglobals
 	return s
 }()
 
-func (h *Handler) renderErrorNice(w http.ResponseWriter, host string, code int, err error) {
-	var message string
+var startingTemplate = template.Must(template.New("").Parse(startingTplHtml)) //nolint:gochecknoglobals
+
+// renderStarting renders the "starting..." page, which reloads itself until the container is ready.
+func (h *Handler) renderStarting(w http.ResponseWriter, host string) {
+	const refreshSeconds = 2
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", strconv.Itoa(refreshSeconds))
+	w.WriteHeader(http.StatusServiceUnavailable)
+
+	if err := startingTemplate.Execute(w, struct {
+		Domain, Version string
+		RefreshSeconds  int
+	}{
+		Domain:         host,
+		Version:        h.appVersion,
+		RefreshSeconds: refreshSeconds,
+	}); err != nil {
+		h.log.Error("failed to render the starting template", zap.Error(err))
+	}
+}
+
+// errorDetails is the error page (or JSON error response) content.
+type errorDetails struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Hostname string `json:"hostname"`
+	Upstream string `json:"upstream,omitempty"` // the upstream URL, if the error is caused by the upstream
+	Details  string `json:"details,omitempty"`  // the upstream error details
+
+	Suggestions []string `json:"suggestions,omitempty"` // the similar registered hostnames ("did you mean")
+}
+
+// renderErrorNice renders the error page (or the JSON error, if the client prefers it).
+func (h *Handler) renderErrorNice(w http.ResponseWriter, r *http.Request, host string, code int, err error) {
+	var message = "Houston, we have a problem"
 
 	if err != nil {
 		message = err.Error()
-	} else {
-		message = "Houston, we have a problem"
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(code)
+	h.renderError(w, r, errorDetails{Code: code, Message: message, Hostname: host})
+}
+
+// renderUpstreamError renders the upstream (container) error, mapping the dial errors, timeouts and TLS errors to
+// the 502, 504 and 526 status codes.
+func (h *Handler) renderUpstreamError(w http.ResponseWriter, r *http.Request, host string, u url.URL, err error) {
+	if r.Context().Err() != nil {
+		return // the client is gone, nothing to respond
+	}
+
+	var code, message = upstreamErrorStatus(err)
+
+	h.log.Debug("Upstream error",
+		zap.String("host", host),
+		zap.String("upstream", u.String()),
+		zap.Int("code", code),
+		zap.Error(err),
+	)
+
+	w.Header().Set("X-Indocker-Downstream-Url", u.String())
 
+	h.renderError(w, r, errorDetails{
+		Code:     code,
+		Message:  message,
+		Hostname: host,
+		Upstream: u.String(),
+		Details:  err.Error(),
+	})
+}
+
+// StatusInvalidSSLCertificate is the (unofficial, but widely used) status code for the upstream TLS errors.
+const StatusInvalidSSLCertificate = 526
+
+// upstreamErrorStatus maps the upstream error to the response status code and the human-readable message.
+func upstreamErrorStatus(err error) (int, string) {
+	var (
+		netErr      net.Error
+		dnsErr      *net.DNSError
+		verifyErr   *tls.CertificateVerificationError
+		authErr     x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+	)
+
+	switch {
+	case errors.Is(err, errInvalidUpstreamTLS):
+		return http.StatusBadGateway, "invalid upstream TLS settings (check the CA bundle and client certificate files)"
+	case errors.As(err, &verifyErr), errors.As(err, &authErr), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return StatusInvalidSSLCertificate, "invalid upstream TLS certificate"
+	case errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return StatusInvalidSSLCertificate, "TLS handshake with the upstream failed (does it serve HTTPS?)"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout, "upstream timed out"
+	case errors.As(err, &dnsErr):
+		return http.StatusBadGateway, "upstream hostname cannot be resolved"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return http.StatusBadGateway, "upstream refused the connection (is the port correct?)"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return http.StatusBadGateway, "upstream closed the connection unexpectedly"
+	}
+
+	return http.StatusBadGateway, "upstream is unavailable"
+}
+
+// wantsJSON returns true if the client prefers the JSON response (e.g. API clients).
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// renderError renders the error page, or the JSON error if the client prefers it.
+func (h *Handler) renderError(w http.ResponseWriter, r *http.Request, e errorDetails) {
 	var (
 		allRoutes  = h.router.AllContainerURLs()       // get all routes
 		allDomains = make([]string, 0, len(allRoutes)) // prepare slice for all domains
@@ -125,20 +511,73 @@ func (h *Handler) renderErrorNice(w http.ResponseWriter, host string, code int,
 
 	slices.SortFunc(allDomains, strings.Compare) // sort hosts
 
-	if execErr := errorTemplate.Execute(w, struct {
-		Code                     int
-		Message, Domain, Version string
-		RegisteredHosts          []string
-		Err4xxSvg, Err5xxSvg     template.HTML
+	if e.Code == http.StatusNotFound && e.Upstream == "" {
+		e.Suggestions = suggestHosts(e.Hostname, allDomains)
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(e.Code)
+
+		if err := json.NewEncoder(w).Encode(e); err != nil {
+			h.log.Error("failed to write the JSON error", zap.Error(err))
+		}
+
+		return
+	}
+
+	var warnings = make([]string, 0)
+
+	// the route conflicts may be the reason why the host is not found, so show them
+	for _, conflict := range h.router.RouteConflicts() {
+		warnings = append(warnings, conflict.String())
+	}
+
+	var tpl = errorTemplate
+
+	if custom := h.errorTemplates.lookup(e.Code); custom != nil {
+		tpl = custom
+	}
+
+	var hasRoutes = len(allDomains) > 0
+
+	if h.hideHosts {
+		allDomains = nil
+	}
+
+	var buf bytes.Buffer // render to the buffer first, to respond with a plain error if the (custom) template fails
+
+	if execErr := tpl.Execute(&buf, struct {
+		Code                                        int
+		Message, Domain, Upstream, Details, Version string
+		HasRoutes                                   bool
+		RegisteredHosts                             []string
+		Suggestions                                 []string
+		Warnings                                    []string
+		Err4xxSvg, Err5xxSvg                        template.HTML
 	}{
-		Code:            code,
-		Message:         message,
-		Domain:          host,
+		Code:            e.Code,
+		Message:         e.Message,
+		Domain:          e.Hostname,
+		Upstream:        e.Upstream,
+		Details:         e.Details,
 		Version:         h.appVersion,
+		HasRoutes:       hasRoutes,
 		RegisteredHosts: allDomains,
+		Suggestions:     e.Suggestions,
+		Warnings:        warnings,
 		Err4xxSvg:       template.HTML(err4xxSvg), //nolint:gosec
 		Err5xxSvg:       template.HTML(err5xxSvg), //nolint:gosec
 	}); execErr != nil {
 		h.log.Error("failed to render error template", zap.Error(execErr))
+
+		http.Error(w, e.Message, e.Code)
+
+		return
 	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(e.Code)
+
+	_, _ = w.Write(buf.Bytes())
 }
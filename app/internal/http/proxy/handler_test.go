@@ -0,0 +1,164 @@
+package proxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+// newRouter creates the running routing table with the given hostname to upstream URL routes.
+func newRouter(t *testing.T, routes map[string]url.URL) *routing.Aggregator {
+	t.Helper()
+
+	var (
+		mem = routing.NewMemoryProvider("test")
+		agg = routing.NewAggregator(zap.NewNop()).Add(mem, 0)
+	)
+
+	for hostname, u := range routes {
+		mem.Set(routing.Route{Hostname: hostname, TargetID: "test:" + hostname, URL: u})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() { _ = agg.Run(ctx) }()
+
+	select {
+	case <-agg.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	return agg
+}
+
+// closedPortAddr returns the address of the (most probably) closed TCP port.
+func closedPortAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var addr = ln.Addr().String()
+
+	require.NoError(t, ln.Close())
+
+	return addr
+}
+
+func TestHandler_ServeHTTP_UpstreamErrors(t *testing.T) {
+	t.Parallel()
+
+	var plainHTTP = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(plainHTTP.Close)
+
+	var router = newRouter(t, map[string]url.URL{
+		"up":       {Scheme: "http", Host: plainHTTP.Listener.Addr().String()},
+		"refused":  {Scheme: "http", Host: closedPortAddr(t)},
+		"tls-fail": {Scheme: "https", Host: plainHTTP.Listener.Addr().String()},
+	})
+
+	var handler = proxy.New(zap.NewNop(), router, nil, "v0.0.0")
+
+	for name, tt := range map[string]struct {
+		giveHost     string
+		wantCode     int
+		wantMessage  string
+		wantUpstream bool
+	}{
+		"ok":            {giveHost: "up.indocker.app", wantCode: http.StatusNoContent},
+		"not found":     {giveHost: "foo.indocker.app", wantCode: http.StatusNotFound, wantMessage: "container not found"},
+		"refused":       {giveHost: "refused.indocker.app", wantCode: http.StatusBadGateway, wantUpstream: true},
+		"tls handshake": {giveHost: "tls-fail.indocker.app", wantCode: proxy.StatusInvalidSSLCertificate, wantUpstream: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			t.Run("json", func(t *testing.T) {
+				t.Parallel()
+
+				var (
+					req = httptest.NewRequest(http.MethodGet, "http://"+tt.giveHost+"/", http.NoBody)
+					rec = httptest.NewRecorder()
+				)
+
+				req.Header.Set("Accept", "application/json")
+
+				handler.ServeHTTP(rec, req)
+
+				assert.Equal(t, tt.wantCode, rec.Code)
+
+				if tt.wantCode < http.StatusBadRequest {
+					return
+				}
+
+				var body struct {
+					Code     int    `json:"code"`
+					Message  string `json:"message"`
+					Hostname string `json:"hostname"`
+					Upstream string `json:"upstream"`
+					Details  string `json:"details"`
+				}
+
+				assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+				assert.Equal(t, tt.wantCode, body.Code)
+				assert.Equal(t, tt.giveHost, body.Hostname)
+				assert.NotEmpty(t, body.Message)
+
+				if tt.wantMessage != "" {
+					assert.Equal(t, tt.wantMessage, body.Message)
+				}
+
+				if tt.wantUpstream {
+					assert.NotEmpty(t, body.Upstream)
+					assert.NotEmpty(t, body.Details)
+					assert.Equal(t, body.Upstream, rec.Header().Get("X-Indocker-Downstream-Url"))
+				} else {
+					assert.Empty(t, body.Upstream)
+				}
+			})
+
+			t.Run("html", func(t *testing.T) {
+				t.Parallel()
+
+				var (
+					req = httptest.NewRequest(http.MethodGet, "http://"+tt.giveHost+"/", http.NoBody)
+					rec = httptest.NewRecorder()
+				)
+
+				req.Header.Set("Accept", "text/html")
+
+				handler.ServeHTTP(rec, req)
+
+				assert.Equal(t, tt.wantCode, rec.Code)
+
+				if tt.wantCode < http.StatusBadRequest {
+					return
+				}
+
+				assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+				assert.Contains(t, rec.Body.String(), "v0.0.0")
+
+				if tt.wantUpstream {
+					assert.Contains(t, rec.Body.String(), rec.Header().Get("X-Indocker-Downstream-Url"))
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// streams tracks the proxied requests, to close the long-lived ones (websockets, SSE) on shutdown. The
+	// [http.Server] does not track the hijacked (upgraded) connections at all, and waits for the streaming responses
+	// until the shutdown timeout.
+	streams struct {
+		mu     sync.Mutex
+		active map[*stream]struct{}
+		closed bool // true after the streams are closed (the server is shutting down)
+	}
+
+	// stream is the tracked proxied request.
+	stream struct {
+		cancel   context.CancelFunc // cancels the request context (it closes the upstream connection, too)
+		longLive bool               // the request is long-lived (websocket, SSE) and must be closed on shutdown
+	}
+)
+
+// streamsPollInterval is how often to check, whether all tracked requests are finished.
+const streamsPollInterval = 50 * time.Millisecond
+
+// track starts tracking the request. It returns the request context, which is canceled when the stream is closed,
+// and the function to call when the request is finished.
+func (s *streams) track(ctx context.Context) (context.Context, *stream, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var st = &stream{cancel: cancel}
+
+	s.mu.Lock()
+	if s.active == nil {
+		s.active = make(map[*stream]struct{})
+	}
+
+	s.active[st] = struct{}{}
+	s.mu.Unlock()
+
+	return ctx, st, func() {
+		s.mu.Lock()
+		delete(s.active, st)
+		s.mu.Unlock()
+
+		cancel()
+	}
+}
+
+// markLongLived marks the request as long-lived, so it's closed on shutdown (immediately, if the shutdown is
+// already in progress).
+func (s *streams) markLongLived(st *stream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st.longLive = true
+
+	if s.closed {
+		st.cancel()
+	}
+}
+
+// close closes the long-lived streams. The regular requests are not affected.
+func (s *streams) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	for st := range s.active {
+		if st.longLive {
+			st.cancel()
+		}
+	}
+}
+
+// wait waits until all tracked requests are finished, or the context is done.
+func (s *streams) wait(ctx context.Context) error {
+	var ticker = time.NewTicker(streamsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		var left = len(s.active)
+		s.mu.Unlock()
+
+		if left == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// isLongLivedResponse returns true if the upstream response is the protocol upgrade (e.g. websocket) or SSE
+// (server-sent events) stream.
+func isLongLivedResponse(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	return mediaType == "text/event-stream"
+}
+
+// isStreamingResponse returns true if the upstream response is long-lived, or its length is unknown (chunked
+// streaming), so it may take longer than the server write timeout.
+func isStreamingResponse(resp *http.Response) bool {
+	return isLongLivedResponse(resp) || resp.ContentLength < 0
+}
+
+// CloseStreams closes the proxied long-lived streams (websockets, SSE), so the server can be stopped. The streams,
+// established after the call, are closed right away. It is intended to be registered using the
+// [http.Server.RegisterOnShutdown].
+func (h *Handler) CloseStreams() { h.streams.close() }
+
+// WaitStreams waits until all proxied requests are finished (including the hijacked websocket connections), or the
+// context is done.
+func (h *Handler) WaitStreams(ctx context.Context) error { return h.streams.wait(ctx) }
@@ -0,0 +1,202 @@
+package proxy_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
+)
+
+// serverTimeout is the (short) proxy server read/write timeout, the streams must outlive.
+const serverTimeout = 300 * time.Millisecond
+
+// newProxyServer starts the proxy server with short timeouts, routing the "app" hostname to the upstream.
+func newProxyServer(t *testing.T, upstream *httptest.Server) (*proxy.Handler, *url.URL) {
+	t.Helper()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	var (
+		handler = proxy.New(zap.NewNop(), newRouter(t, map[string]url.URL{"app": *upstreamURL}), nil, "v0.0.0")
+		srv     = httptest.NewUnstartedServer(handler)
+	)
+
+	srv.Config.ReadTimeout, srv.Config.WriteTimeout = serverTimeout, serverTimeout
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	proxyURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	return handler, proxyURL
+}
+
+func TestHandler_WebSocket(t *testing.T) {
+	t.Parallel()
+
+	var upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		defer func() { _ = conn.Close() }()
+
+		for { // echo
+			msgType, msg, readErr := conn.ReadMessage()
+			if readErr != nil {
+				return
+			}
+
+			if writeErr := conn.WriteMessage(msgType, msg); writeErr != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	var handler, proxyURL = newProxyServer(t, upstream)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(
+		"ws://"+proxyURL.Host+"/ws",
+		http.Header{"Host": {"app.indocker.app"}},
+	)
+	require.NoError(t, err)
+
+	defer func() { _ = conn.Close() }()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.NoError(t, resp.Body.Close())
+
+	var echo = func(msg string) {
+		t.Helper()
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+
+		_, got, readErr := conn.ReadMessage()
+		require.NoError(t, readErr)
+		assert.Equal(t, msg, string(got))
+	}
+
+	echo("hello")
+
+	time.Sleep(serverTimeout * 2) // the connection must outlive the server timeouts
+
+	echo("world")
+
+	// on shutdown, the connection is closed
+	handler.CloseStreams()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, handler.WaitStreams(ctx))
+}
+
+func TestHandler_Streaming(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveContentType string
+		giveChunks      [2]string
+		wantClosed      bool // the stream is long-lived, and closed on shutdown
+	}{
+		"sse": {
+			giveContentType: "text/event-stream; charset=utf-8",
+			giveChunks:      [2]string{"data: first\n", "data: second\n"},
+			wantClosed:      true,
+		},
+		"chunked": {
+			giveContentType: "text/plain",
+			giveChunks:      [2]string{"first\n", "second\n"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var release = make(chan struct{})
+
+			var upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.giveContentType)
+
+				_, _ = io.WriteString(w, tt.giveChunks[0])
+				_ = http.NewResponseController(w).Flush()
+
+				select {
+				case <-release:
+				case <-r.Context().Done():
+					return
+				}
+
+				_, _ = io.WriteString(w, tt.giveChunks[1])
+				_ = http.NewResponseController(w).Flush()
+
+				if tt.wantClosed {
+					<-r.Context().Done() // keep the stream open
+				}
+			}))
+			t.Cleanup(upstream.Close)
+
+			var handler, proxyURL = newProxyServer(t, upstream)
+
+			req, err := http.NewRequest(http.MethodGet, proxyURL.String()+"/stream", http.NoBody)
+			require.NoError(t, err)
+
+			req.Host = "app.indocker.app"
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var body = bufio.NewReader(resp.Body)
+
+			// the first chunk is flushed immediately, while the upstream is still waiting
+			line, err := body.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, tt.giveChunks[0], line)
+
+			time.Sleep(serverTimeout * 2) // the stream must outlive the server timeouts
+			close(release)
+
+			line, err = body.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, tt.giveChunks[1], line)
+
+			if tt.wantClosed {
+				handler.CloseStreams() // on shutdown, the stream is aborted
+
+				_, err = io.ReadAll(body)
+				assert.Error(t, err)
+			} else {
+				rest, readErr := io.ReadAll(body)
+				require.NoError(t, readErr)
+				assert.Empty(t, rest)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			assert.NoError(t, handler.WaitStreams(ctx))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"slices"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of the "did you mean" suggestions.
+const maxSuggestions = 3
+
+// suggestHosts returns the registered hostnames, similar to the requested one (typos, missing or extra characters),
+// ordered by the similarity.
+func suggestHosts(host string, registered []string) []string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".indocker.app")
+
+	if host == "" {
+		return nil
+	}
+
+	// the longer the hostname, the more typos are tolerated
+	var maxDistance = min(3, max(1, len(host)/3)) //nolint:mnd
+
+	type candidate struct {
+		host     string
+		distance int
+	}
+
+	var candidates = make([]candidate, 0)
+
+	for _, r := range registered {
+		if r == host {
+			continue
+		}
+
+		if d := editDistance(host, r); d <= maxDistance {
+			candidates = append(candidates, candidate{host: r, distance: d})
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+
+		return strings.Compare(a.host, b.host)
+	})
+
+	var suggestions = make([]string, 0, min(len(candidates), maxSuggestions))
+
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].host)
+	}
+
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between the strings (the number of single character insertions,
+// deletions or substitutions needed to change one string into the other).
+func editDistance(a, b string) int {
+	var ra, rb = []rune(a), []rune(b)
+
+	var prev, curr = make([]int, len(rb)+1), make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			var cost = 1
+
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveA, giveB string
+		want         int
+	}{
+		"equal":        {giveA: "app", giveB: "app", want: 0},
+		"empty":        {giveA: "", giveB: "app", want: 3},
+		"substitution": {giveA: "app", giveB: "apq", want: 1},
+		"insertion":    {giveA: "app", giveB: "apps", want: 1},
+		"deletion":     {giveA: "grafana", giveB: "grafna", want: 1},
+		"transposed":   {giveA: "grafana", giveB: "garfana", want: 2},
+		"different":    {giveA: "foo", giveB: "bar", want: 3},
+		"unicode":      {giveA: "café", giveB: "cafe", want: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, editDistance(tt.giveA, tt.giveB))
+			assert.Equal(t, tt.want, editDistance(tt.giveB, tt.giveA))
+		})
+	}
+}
+
+func TestSuggestHosts(t *testing.T) {
+	t.Parallel()
+
+	var registered = []string{"api", "app", "apps", "grafana", "prometheus", "web1", "web2", "web3", "web4", "whoami"}
+
+	for name, tt := range map[string]struct {
+		giveHost string
+		want     []string
+	}{
+		"typo":            {giveHost: "grafna.indocker.app", want: []string{"grafana"}},
+		"closest first":   {giveHost: "appz", want: []string{"app", "apps"}},
+		"at most three":   {giveHost: "webx", want: []string{"web1", "web2", "web3"}},
+		"too different":   {giveHost: "postgres", want: []string{}},
+		"exact is routed": {giveHost: "whoami", want: []string{}},
+		"empty":           {giveHost: ".indocker.app"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, suggestHosts(tt.giveHost, registered))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrorTemplates are the user-defined error page templates, keyed by the status code ("404"), the status class
+// ("4xx") or "error" (for any status). They get the same data as the embedded error page template.
+type ErrorTemplates map[string]*template.Template
+
+// errorTemplateFallback is the name of the template, used for any status code.
+const errorTemplateFallback = "error"
+
+// LoadErrorTemplates loads the error page templates from the directory. The files are named by the status code
+// (e.g. "404.html", "502.html"), the status class ("4xx.html", "5xx.html") or "error.html" (used for any status).
+// Other files are ignored.
+func LoadErrorTemplates(dir string) (ErrorTemplates, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the error templates directory: %w", err)
+	}
+
+	var templates = make(ErrorTemplates)
+
+	for _, entry := range entries {
+		var name, ext = strings.CutSuffix(strings.ToLower(entry.Name()), ".html")
+
+		if entry.IsDir() || !ext || !isErrorTemplateName(name) {
+			continue
+		}
+
+		content, readErr := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read the error template %s: %w", entry.Name(), readErr)
+		}
+
+		tpl, parseErr := template.New(name).Parse(string(content))
+		if parseErr != nil {
+			return nil, fmt.Errorf("failed to parse the error template %s: %w", entry.Name(), parseErr)
+		}
+
+		templates[name] = tpl
+	}
+
+	if len(templates) == 0 {
+		return nil, errors.New("no error templates (e.g. 404.html, 5xx.html or error.html) found in " + dir)
+	}
+
+	return templates, nil
+}
+
+// isErrorTemplateName returns true if the name (without extension) is the status code, the status class or the
+// fallback name.
+func isErrorTemplateName(name string) bool {
+	if name == errorTemplateFallback {
+		return true
+	}
+
+	if len(name) != 3 { //nolint:mnd
+		return false
+	}
+
+	if class, isClass := strings.CutSuffix(name, "xx"); isClass {
+		return class >= "1" && class <= "5"
+	}
+
+	code, err := strconv.Atoi(name)
+
+	return err == nil && code >= 100 && code <= 599
+}
+
+// lookup returns the most specific template for the status code (the code itself, then the class, then the
+// fallback), or nil if there is none.
+func (t ErrorTemplates) lookup(code int) *template.Template {
+	for _, name := range []string{strconv.Itoa(code), strconv.Itoa(code/100) + "xx", errorTemplateFallback} { //nolint:mnd
+		if tpl, ok := t[name]; ok {
+			return tpl
+		}
+	}
+
+	return nil
+}
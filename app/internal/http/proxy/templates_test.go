@@ -0,0 +1,74 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
+)
+
+func TestLoadErrorTemplates(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+
+		var dir = t.TempDir()
+
+		for name, content := range map[string]string{
+			"404.html":   `not found: {{ .Domain }}{{ range .Suggestions }} {{ . }}{{ end }}`,
+			"5xx.html":   `server error {{ .Code }}`,
+			"readme.txt": `ignored`,
+			"foo.html":   `ignored`,
+		} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+		}
+
+		templates, err := proxy.LoadErrorTemplates(dir)
+		require.NoError(t, err)
+		assert.Len(t, templates, 2)
+
+		var handler = proxy.New(zap.NewNop(), newRouter(t, nil), nil, "v0.0.0",
+			proxy.WithErrorTemplates(templates),
+		)
+
+		var rec = httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://foo.indocker.app/", http.NoBody))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "not found: foo.indocker.app", rec.Body.String())
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		t.Parallel()
+
+		var dir = t.TempDir()
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "error.html"), []byte(`{{ .Code`), 0o600))
+
+		_, err := proxy.LoadErrorTemplates(dir)
+		assert.ErrorContains(t, err, "failed to parse the error template error.html")
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := proxy.LoadErrorTemplates(t.TempDir())
+		assert.ErrorContains(t, err, "no error templates")
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := proxy.LoadErrorTemplates(filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type (
+	// transports are the upstream transports, shared by all the proxied requests (to reuse the connections) with the
+	// same upstream TLS settings.
+	transports struct {
+		http1 *http.Transport // HTTP/1.1 (or HTTP/2, if negotiated using TLS ALPN) for the http and https schemes
+		h2c   *http.Transport // HTTP/2 without TLS (prior knowledge) for the h2c and grpc schemes
+		h2    *http.Transport // HTTP/2 over TLS for the grpcs scheme
+	}
+
+	// upstreamTransports keeps the transports by the upstream TLS settings. The transports are created on the first
+	// use, and re-created once the CA bundle or the client certificate files are changed. The transports, which are
+	// not used for a while (e.g. the route is removed), are closed.
+	upstreamTransports struct {
+		mu    sync.Mutex
+		byTLS map[docker.UpstreamTLS]*cachedTransports // the zero key is for the default settings (never removed)
+		now   func() time.Time
+	}
+
+	// cachedTransports are the transports, created for the specific upstream TLS settings.
+	cachedTransports struct {
+		transports
+
+		files    string    // the state of the files, used to create the transports (see [filesState])
+		lastUsed time.Time // the last time the transports were requested
+	}
+)
+
+const (
+	idleConnTimeout     = 90 * time.Second // how long the idle upstream connection is kept
+	unusedTransportsTTL = 10 * time.Minute // the transports, not used for this period, are closed
+)
+
+// errInvalidUpstreamTLS is returned when the upstream TLS settings can't be applied (e.g. the CA bundle is missing).
+var errInvalidUpstreamTLS = errors.New("invalid upstream TLS settings")
+
+func newTransports(tlsConfig *tls.Config) transports {
+	var t = transports{
+		http1: &http.Transport{TLSClientConfig: tlsConfig.Clone(), IdleConnTimeout: idleConnTimeout},
+		h2c:   &http.Transport{Protocols: new(http.Protocols), IdleConnTimeout: idleConnTimeout},
+		h2: &http.Transport{
+			Protocols: new(http.Protocols), TLSClientConfig: tlsConfig.Clone(), IdleConnTimeout: idleConnTimeout,
+		},
+	}
+
+	t.h2c.Protocols.SetUnencryptedHTTP2(true)
+	t.h2.Protocols.SetHTTP2(true)
+
+	return t
+}
+
+// closeIdle closes the idle connections of the transports (the active ones are closed once they are done).
+func (t transports) closeIdle() {
+	t.http1.CloseIdleConnections()
+	t.h2c.CloseIdleConnections()
+	t.h2.CloseIdleConnections()
+}
+
+func newUpstreamTransports() *upstreamTransports {
+	return &upstreamTransports{
+		byTLS: map[docker.UpstreamTLS]*cachedTransports{
+			{}: {transports: newTransports(&tls.Config{InsecureSkipVerify: true})}, //nolint:gosec // backward compatibility
+		},
+		now: time.Now,
+	}
+}
+
+// get returns the transports for the upstream TLS settings.
+func (u *upstreamTransports) get(settings docker.UpstreamTLS) (transports, error) {
+	var files = filesState(settings)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var now = u.now()
+
+	u.removeUnused(now)
+
+	if c, ok := u.byTLS[settings]; ok {
+		if c.files == files {
+			c.lastUsed = now
+
+			return c.transports, nil
+		}
+
+		// the files are changed (e.g. the certificate is renewed), so the transports must be re-created
+		c.closeIdle()
+		delete(u.byTLS, settings)
+	}
+
+	tlsConfig, err := upstreamTLSConfig(settings)
+	if err != nil {
+		return transports{}, fmt.Errorf("%w: %w", errInvalidUpstreamTLS, err) // not cached, the files may be fixed
+	}
+
+	var c = &cachedTransports{transports: newTransports(tlsConfig), files: files, lastUsed: now}
+
+	u.byTLS[settings] = c
+
+	return c.transports, nil
+}
+
+// removeUnused closes and removes the transports, which are not used for a while. The mu must be locked.
+func (u *upstreamTransports) removeUnused(now time.Time) {
+	for settings, c := range u.byTLS {
+		if !settings.IsZero() && now.Sub(c.lastUsed) > unusedTransportsTTL {
+			c.closeIdle()
+			delete(u.byTLS, settings)
+		}
+	}
+}
+
+// filesState returns the state (the size and the modification time) of the files, set in the upstream TLS settings.
+// Once any file is changed, the state is changed too.
+func filesState(settings docker.UpstreamTLS) string {
+	var b strings.Builder
+
+	for _, name := range []string{settings.CAFile, settings.CertFile, settings.KeyFile} {
+		if name == "" {
+			continue
+		}
+
+		if info, err := os.Stat(name); err == nil {
+			_, _ = fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		} else {
+			_, _ = fmt.Fprintf(&b, "%s:-;", name)
+		}
+	}
+
+	return b.String()
+}
+
+// upstreamTLSConfig returns the TLS client config for the upstream TLS settings.
+func upstreamTLSConfig(settings docker.UpstreamTLS) (*tls.Config, error) {
+	var cfg = &tls.Config{ //nolint:gosec // the verification is disabled by default
+		InsecureSkipVerify: !settings.Verify,
+		ServerName:         settings.ServerName,
+	}
+
+	if settings.CAFile != "" {
+		data, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA bundle: %w", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+
+		if !cfg.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in the CA bundle %s", settings.CAFile)
+		}
+	}
+
+	if settings.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// forScheme returns the transport for the upstream scheme, and the scheme of the upstream request URL (the
+// transports understand only http and https).
+func (t transports) forScheme(scheme string) (http.RoundTripper, string) {
+	switch {
+	case docker.IsHTTP2Scheme(scheme) && docker.IsTLSScheme(scheme):
+		return t.h2, docker.SchemeHTTPS
+	case docker.IsHTTP2Scheme(scheme):
+		return t.h2c, docker.SchemeHTTP
+	case docker.IsTLSScheme(scheme):
+		return t.http1, docker.SchemeHTTPS
+	}
+
+	return t.http1, docker.SchemeHTTP
+}
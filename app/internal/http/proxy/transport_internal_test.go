@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+func TestUpstreamTransports_get(t *testing.T) {
+	t.Parallel()
+
+	var srv = httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	var (
+		caFile  = filepath.Join(t.TempDir(), "ca.pem")
+		caPEM   = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		now     = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		u       = newUpstreamTransports()
+		withCA  = docker.UpstreamTLS{Verify: true, CAFile: caFile}
+		another = docker.UpstreamTLS{Verify: true}
+	)
+
+	u.now = func() time.Time { return now }
+
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	first, err := u.get(withCA)
+	require.NoError(t, err)
+
+	cached, err := u.get(withCA)
+	require.NoError(t, err)
+	assert.Same(t, first.http1, cached.http1, "the transports are reused")
+
+	// the CA bundle is changed (e.g. renewed)
+	require.NoError(t, os.WriteFile(caFile, append(caPEM, '\n'), 0o600))
+
+	reloaded, err := u.get(withCA)
+	require.NoError(t, err)
+	assert.NotSame(t, first.http1, reloaded.http1, "the transports are re-created")
+
+	// the transports, not used for a while, are removed (except the default ones)
+	now = now.Add(unusedTransportsTTL + time.Second)
+
+	_, err = u.get(another)
+	require.NoError(t, err)
+
+	assert.Len(t, u.byTLS, 2)
+	assert.Contains(t, u.byTLS, docker.UpstreamTLS{})
+	assert.Contains(t, u.byTLS, another)
+
+	// the missing files are not cached
+	require.NoError(t, os.Remove(caFile))
+
+	_, err = u.get(withCA)
+	assert.ErrorIs(t, err, errInvalidUpstreamTLS)
+	assert.NotContains(t, u.byTLS, withCA)
+}
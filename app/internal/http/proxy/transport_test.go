@@ -0,0 +1,120 @@
+package proxy_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestHandler_UpstreamTLS(t *testing.T) {
+	t.Parallel()
+
+	// the upstream requires the client certificate (any), and reports the requested server name
+	var upstream = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "sni=%s clients=%d", r.TLS.ServerName, len(r.TLS.PeerCertificates))
+	}))
+
+	upstream.TLS = &tls.Config{ClientAuth: tls.RequestClientCert} //nolint:gosec
+	upstream.StartTLS()
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	// write the upstream certificate (it's self-signed, so it's the CA bundle too) and key to the files, the same
+	// pair is used as the client certificate
+	var (
+		dir      = t.TempDir()
+		certFile = filepath.Join(dir, "cert.pem")
+		keyFile  = filepath.Join(dir, "key.pem")
+		cert     = upstream.TLS.Certificates[0]
+	)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+
+	var (
+		certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+		keyPEM  = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	)
+
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+
+	var (
+		mem    = routing.NewMemoryProvider("test")
+		router = routing.NewAggregator(zap.NewNop()).Add(mem, 0)
+	)
+
+	for hostname, settings := range map[string]docker.UpstreamTLS{
+		"insecure":  {},
+		"verify":    {Verify: true},
+		"ca":        {Verify: true, CAFile: certFile},
+		"ca-sni":    {Verify: true, CAFile: certFile, ServerName: "example.com"},
+		"client":    {CertFile: certFile, KeyFile: keyFile},
+		"no-bundle": {Verify: true, CAFile: filepath.Join(dir, "missing.pem")},
+	} {
+		mem.Set(routing.Route{
+			Hostname: hostname,
+			TargetID: "test:" + hostname,
+			URL:      *upstreamURL,
+			Options:  docker.RouteOptions{TLS: settings},
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() { _ = router.Run(ctx) }()
+
+	<-router.Ready()
+
+	var handler = proxy.New(zap.NewNop(), router, nil, "v0.0.0")
+
+	for name, tt := range map[string]struct {
+		wantCode int
+		wantBody string
+	}{
+		"insecure": {wantCode: http.StatusOK, wantBody: "sni= clients=0"},
+		"verify":   {wantCode: proxy.StatusInvalidSSLCertificate}, // not trusted by the system
+		"ca": { // the certificate is valid for 127.0.0.1, too
+			wantCode: http.StatusOK, wantBody: "sni= clients=0",
+		},
+		"ca-sni":    {wantCode: http.StatusOK, wantBody: "sni=example.com clients=0"},
+		"client":    {wantCode: http.StatusOK, wantBody: "sni= clients=1"},
+		"no-bundle": {wantCode: http.StatusBadGateway, wantBody: "invalid upstream TLS settings"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				req = httptest.NewRequest(http.MethodGet, "http://"+name+".indocker.app/", nil)
+				rec = httptest.NewRecorder()
+			)
+
+			handler.ServeHTTP(rec, req)
+
+			body, _ := io.ReadAll(rec.Body)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, string(body), tt.wantBody)
+		})
+	}
+}
@@ -10,12 +10,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/docker/client"
+	"github.com/quic-go/quic-go/http3"
 	"go.uber.org/zap"
 
-	"gh.tarampamp.am/indocker-app/app/internal/docker"
 	"gh.tarampamp.am/indocker-app/app/internal/http/middleware/frontend"
 	"gh.tarampamp.am/indocker-app/app/internal/http/middleware/logreq"
 	"gh.tarampamp.am/indocker-app/app/internal/http/openapi"
+	"gh.tarampamp.am/indocker-app/app/internal/http/passthrough"
 	"gh.tarampamp.am/indocker-app/app/internal/http/proxy"
 	"gh.tarampamp.am/indocker-app/app/internal/version"
 	"gh.tarampamp.am/indocker-app/app/web"
@@ -24,6 +26,17 @@ import (
 type Server struct {
 	http  *http.Server
 	https *http.Server
+	http3 *http3.Server // optional HTTP/3 (QUIC) server, serving the same handler as the HTTPS server
+
+	isPassthrough func(hostname string) bool // reports whether the hostname uses the TLS passthrough (set on Register)
+
+	readOnlyAPI      bool          // disables the API methods that change something (e.g. container actions)
+	routeGracePeriod time.Duration // how long to hold the requests to the recently removed routes
+
+	errorTemplates proxy.ErrorTemplates // user-defined error page templates (optional)
+	hideHosts      bool                 // do not list the registered hosts on the error pages
+
+	proxies map[*http.Server]*proxy.Handler // the proxy handlers, used by the servers (to close the streams)
 
 	ShutdownTimeout time.Duration // Maximum amount of time to wait for the server to stop, default is 5 seconds
 }
@@ -42,6 +55,36 @@ func WithIDLETimeout(d time.Duration) ServerOption {
 	return func(s *Server) { s.http.IdleTimeout = d; s.https.IdleTimeout = d }
 }
 
+// WithReadOnlyAPI disables the API methods that change something (e.g. start/stop containers).
+func WithReadOnlyAPI(readOnly bool) ServerOption {
+	return func(s *Server) { s.readOnlyAPI = readOnly }
+}
+
+// WithRouteGracePeriod sets how long to hold the requests to the recently removed routes (e.g. the container is
+// being recreated), waiting for the route to come back. Zero disables the holding.
+func WithRouteGracePeriod(d time.Duration) ServerOption {
+	return func(s *Server) { s.routeGracePeriod = d }
+}
+
+// WithErrorTemplates sets the user-defined error page templates (see [proxy.LoadErrorTemplates]).
+func WithErrorTemplates(t proxy.ErrorTemplates) ServerOption {
+	return func(s *Server) { s.errorTemplates = t }
+}
+
+// WithHiddenHosts hides the list of the registered hosts on the error pages.
+func WithHiddenHosts(hide bool) ServerOption {
+	return func(s *Server) { s.hideHosts = hide }
+}
+
+// WithHTTP3 enables the HTTP/3 (QUIC) server, which serves the same handler as the HTTPS server. It's advertised
+// using the Alt-Svc header on the HTTPS responses, with the given port (zero means the port the server listens on,
+// which may differ from the public one, e.g. inside the container).
+func WithHTTP3(advertisedPort uint16) ServerOption {
+	return func(s *Server) {
+		s.http3 = &http3.Server{Port: int(advertisedPort)}
+	}
+}
+
 func NewServer(baseCtx context.Context, log *zap.Logger, opts ...ServerOption) *Server {
 	var (
 		server = Server{
@@ -53,7 +96,8 @@ func NewServer(baseCtx context.Context, log *zap.Logger, opts ...ServerOption) *
 				BaseContext: func(net.Listener) context.Context { return baseCtx },
 				ErrorLog:    zap.NewStdLog(log.Named("https")),
 			},
-			ShutdownTimeout: 5 * time.Second, //nolint:mnd
+			ShutdownTimeout: 5 * time.Second,                          //nolint:mnd
+			proxies:         make(map[*http.Server]*proxy.Handler, 2), //nolint:mnd
 		}
 	)
 
@@ -64,11 +108,18 @@ func NewServer(baseCtx context.Context, log *zap.Logger, opts ...ServerOption) *
 	return &server
 }
 
-func (s *Server) Register(ctx context.Context, log *zap.Logger, router interface {
-	docker.RoutingUpdateSubscriber
-	docker.RoutingURLResolver
-	docker.AllContainerURLsResolver
-}, useLiveFrontend bool) *Server {
+// Register registers the handlers. The router is the (aggregated) routing table, used by the proxy and the routes
+// API, while the docker state is used by the container-related API methods. Runtime routes are managed using the
+// API, and must be registered in the router as a routes provider.
+func (s *Server) Register(
+	ctx context.Context,
+	log *zap.Logger,
+	router router,
+	dockerState dockerState,
+	runtimeRoutes runtimeRoutes,
+	dockerClient client.ContainerAPIClient,
+	useLiveFrontend bool,
+) *Server {
 	var frontendFs = web.Dist(useLiveFrontend)
 
 	// since both servers uses the same logics, we can iterate over them, but with differently named loggers
@@ -78,7 +129,7 @@ func (s *Server) Register(ctx context.Context, log *zap.Logger, router interface
 	} {
 		var (
 			// create openapi server implementation (it is used only for the monitor subdomain)
-			openapiServer = NewOpenAPI(ctx, namedLog, router)
+			openapiServer = NewOpenAPI(ctx, namedLog, router, dockerState, runtimeRoutes, dockerClient, s.readOnlyAPI)
 
 			// create the base router for the openapi server
 			openapiMux = http.NewServeMux()
@@ -87,7 +138,10 @@ func (s *Server) Register(ctx context.Context, log *zap.Logger, router interface
 			openapiHandler = openapi.HandlerWithOptions(openapiServer, openapi.StdHTTPServerOptions{
 				ErrorHandlerFunc: openapiServer.HandleInternalError,
 				BaseRouter:       openapiMux,
-				Middlewares:      []openapi.MiddlewareFunc{openapi.CorsMiddleware()},
+				Middlewares: []openapi.MiddlewareFunc{
+					openapi.CorsMiddleware(),
+					openapi.SameOriginMiddleware(), // CSRF protection for the methods, which change something
+				},
 			})
 		)
 
@@ -103,7 +157,16 @@ func (s *Server) Register(ctx context.Context, log *zap.Logger, router interface
 			return strings.HasPrefix(r.URL.Path, "/api") // skip the middleware, if the request is intended for the API
 		})(http.HandlerFunc(openapiServer.HandleNotFoundError))) // <-- this is the general 404 handler
 
-		var proxyHandler = proxy.New(namedLog, router, version.Version())
+		var proxyHandler = proxy.New(namedLog, router, dockerState, version.Version(),
+			proxy.WithGracePeriod(s.routeGracePeriod),
+			proxy.WithErrorTemplates(s.errorTemplates),
+			proxy.WithHiddenHosts(s.hideHosts),
+		)
+
+		// the server does not track the hijacked (websocket) connections, and waits for the streaming responses
+		// (SSE) to finish, so the proxied streams must be closed on shutdown
+		srv.RegisterOnShutdown(proxyHandler.CloseStreams)
+		s.proxies[srv] = proxyHandler
 
 		// wrap the server handler with middleware
 		srv.Handler = logreq.New(namedLog, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -132,6 +195,34 @@ func (s *Server) Register(ctx context.Context, log *zap.Logger, router interface
 		}))
 	}
 
+	s.isPassthrough = func(hostname string) bool { _, ok := passthrough.Upstream(router, hostname); return ok }
+
+	if s.http3 != nil {
+		var httpsHandler = s.https.Handler
+
+		// the same handler chain serves both, except the TLS passthrough hostnames - the HTTP/3 server terminates TLS,
+		// so such requests (e.g. sent using the connection, opened for another hostname) are misdirected
+		s.http3.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.isPassthrough(requestHostname(r)) {
+				http.Error(w, "HTTP/3 is not available for the TLS passthrough hostname", http.StatusMisdirectedRequest)
+
+				return
+			}
+
+			httpsHandler.ServeHTTP(w, r)
+		})
+		s.http3.IdleTimeout = s.https.IdleTimeout
+
+		// advertise the HTTP/3 support on the HTTPS responses, so the clients can switch to it
+		s.https.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.isPassthrough(requestHostname(r)) {
+				_ = s.http3.SetQUICHeaders(w.Header()) // fails only if the HTTP/3 server is not started yet
+			}
+
+			httpsHandler.ServeHTTP(w, r)
+		})
+	}
+
 	return s
 }
 
@@ -146,10 +237,7 @@ func (s *Server) StartHTTP(ctx context.Context, ln net.Listener) error {
 
 	select {
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.ShutdownTimeout)
-		defer cancel()
-
-		if err := s.http.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := s.shutdown(ctx, s.http); err != nil {
 			return err
 		}
 	case err, isOpened := <-errCh:
@@ -181,22 +269,92 @@ func (s *Server) StartHTTPs(ctx context.Context, ln net.Listener, cert tls.Certi
 
 	go func(ch chan<- error) { defer close(ch); ch <- s.https.ServeTLS(ln, "", "") }(errCh)
 
+	select {
+	case <-ctx.Done():
+		if err := s.shutdown(ctx, s.https); err != nil {
+			return err
+		}
+	case err, isOpened := <-errCh:
+		switch {
+		case !isOpened:
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartHTTP3 starts the HTTP/3 (QUIC) server, if it's enabled (see [WithHTTP3]). It serves incoming requests using
+// the provided UDP connection (it's not closed by the server). To stop the server, cancel the provided context.
+//
+// It blocks until the context is canceled or the server is stopped by some error.
+func (s *Server) StartHTTP3(ctx context.Context, conn net.PacketConn, cert tls.Certificate) error {
+	if s.http3 == nil {
+		return errors.New("HTTP/3 server is not enabled")
+	}
+
+	s.http3.TLSConfig = http3.ConfigureTLSConfig(&tls.Config{
+		MinVersion:   tls.VersionTLS13, // required by QUIC
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			// the TLS passthrough hostnames are terminated by the containers, so QUIC is refused (the clients fall
+			// back to TCP, where the connection is passed through)
+			if s.isPassthrough != nil && s.isPassthrough(hello.ServerName) {
+				return nil, fmt.Errorf("the %s hostname uses TLS passthrough, QUIC is not supported", hello.ServerName)
+			}
+
+			return nil, nil // use the base config
+		},
+	})
+
+	var errCh = make(chan error)
+
+	go func(ch chan<- error) { defer close(ch); ch <- s.http3.Serve(conn) }(errCh)
+
 	select {
 	case <-ctx.Done():
 		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.ShutdownTimeout)
 		defer cancel()
 
-		if err := s.https.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := s.http3.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			return err
 		}
 	case err, isOpened := <-errCh:
 		switch {
 		case !isOpened:
 			return nil
-		case err != nil:
+		case err != nil && !errors.Is(err, http.ErrServerClosed):
 			return err
 		}
 	}
 
 	return nil
 }
+
+// requestHostname returns the request hostname without the port.
+func requestHostname(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+
+	return r.Host
+}
+
+// shutdown gracefully stops the server, and waits for the proxied requests (including the hijacked websocket
+// connections, which are not tracked by the server) to finish.
+func (s *Server) shutdown(ctx context.Context, srv *http.Server) error {
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if p, ok := s.proxies[srv]; ok {
+		return p.WaitStreams(shutdownCtx)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package http_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	appHttp "gh.tarampamp.am/indocker-app/app/internal/http"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestServer_HTTP3(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto) // the protocol of the upstream request (always HTTP/1.1)
+	}))
+	t.Cleanup(upstream.Close)
+
+	// borrow the self-signed certificate from the test TLS server
+	var tlsSrv = httptest.NewTLSServer(http.NotFoundHandler())
+	var cert = tlsSrv.TLS.Certificates[0]
+
+	tlsSrv.Close()
+
+	// the routing table with the regular and TLS passthrough routes
+	var (
+		mem    = routing.NewMemoryProvider("test")
+		router = routing.NewAggregator(zap.NewNop()).Add(mem, 0)
+	)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	mem.Set(routing.Route{Hostname: "app", TargetID: "test:app", URL: *upstreamURL})
+	mem.Set(routing.Route{
+		Hostname: "vault",
+		TargetID: "test:vault",
+		URL:      *upstreamURL,
+		Options:  docker.RouteOptions{Passthrough: true},
+	})
+
+	go func() { _ = router.Run(ctx) }()
+
+	<-router.Ready()
+
+	var server = appHttp.NewServer(ctx, zap.NewNop(), appHttp.WithHTTP3(443)).
+		Register(ctx, zap.NewNop(), router, nil, nil, nil, false)
+
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	udpConn, err := net.ListenPacket("udp", tcpLn.Addr().String())
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = udpConn.Close() })
+
+	go func() { _ = server.StartHTTPs(ctx, tcpLn, cert) }()
+	go func() { _ = server.StartHTTP3(ctx, udpConn, cert) }()
+
+	var tlsConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+
+	t.Run("https advertises http/3", func(t *testing.T) {
+		t.Parallel()
+
+		var client = http.Client{Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, tcpLn.Addr().String())
+			},
+		}}
+
+		assert.EventuallyWithT(t, func(c *assert.CollectT) {
+			resp, reqErr := client.Get("https://app.indocker.app/")
+			require.NoError(c, reqErr)
+
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(c, http.StatusOK, resp.StatusCode)
+			assert.Equal(c, `h3=":443"; ma=2592000`, resp.Header.Get("Alt-Svc"))
+		}, 5*time.Second, 50*time.Millisecond)
+
+		// the passthrough hostname is not advertised (the listener is not wrapped here, so the request is handled)
+		assert.EventuallyWithT(t, func(c *assert.CollectT) {
+			resp, reqErr := client.Get("https://vault.indocker.app/")
+			require.NoError(c, reqErr)
+
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(c, http.StatusOK, resp.StatusCode)
+			assert.Empty(c, resp.Header.Get("Alt-Svc"))
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("http/3", func(t *testing.T) {
+		t.Parallel()
+
+		var transport = &http3.Transport{
+			TLSClientConfig: tlsConfig,
+			Dial: func(ctx context.Context, _ string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+				return quic.DialAddrEarly(ctx, udpConn.LocalAddr().String(), tlsCfg, cfg)
+			},
+		}
+
+		t.Cleanup(func() { _ = transport.Close() })
+
+		var client = http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+		resp, reqErr := client.Get("https://app.indocker.app/")
+		require.NoError(t, reqErr)
+
+		defer func() { _ = resp.Body.Close() }()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "HTTP/3.0", resp.Proto)
+		assert.Equal(t, "HTTP/1.1", string(body))
+	})
+
+	t.Run("http/3 refused for passthrough", func(t *testing.T) {
+		t.Parallel()
+
+		var transport = &http3.Transport{
+			TLSClientConfig: tlsConfig,
+			Dial: func(ctx context.Context, _ string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+				return quic.DialAddrEarly(ctx, udpConn.LocalAddr().String(), tlsCfg, cfg)
+			},
+		}
+
+		t.Cleanup(func() { _ = transport.Close() })
+
+		var client = http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+		resp, reqErr := client.Get("https://vault.indocker.app/")
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		require.Error(t, reqErr)
+	})
+}
@@ -0,0 +1,118 @@
+package routesfile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+type (
+	httpClient interface {
+		Do(*http.Request) (*http.Response, error)
+	}
+
+	// HTTPProvider provides the routes, fetched (polled) from the remote HTTP endpoint. The endpoint must respond
+	// with the JSON document in the same format as the routes file.
+	HTTPProvider struct {
+		log      *zap.Logger
+		url      string
+		interval time.Duration
+		client   httpClient
+	}
+
+	// HTTPProviderOption allows to change the [HTTPProvider] options.
+	HTTPProviderOption func(*HTTPProvider)
+)
+
+var _ routing.Provider = (*HTTPProvider)(nil) // verify interface implementation
+
+// WithHTTPClient sets the HTTP client.
+func WithHTTPClient(c httpClient) HTTPProviderOption { return func(p *HTTPProvider) { p.client = c } }
+
+// maxResponseSize limits the size of the routes document.
+const maxResponseSize = 1 << 20 // 1 MiB
+
+// NewHTTPProvider creates a new HTTP provider, which polls the URL with the given interval.
+func NewHTTPProvider(log *zap.Logger, url string, interval time.Duration, opts ...HTTPProviderOption) *HTTPProvider {
+	var p = &HTTPProvider{
+		log:      log,
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second}, //nolint:mnd
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Name returns the provider name.
+func (*HTTPProvider) Name() string { return string(docker.RouteSourceHTTP) }
+
+// Watch polls the remote endpoint. Failed requests (or invalid documents) are logged, and the previously fetched
+// routes are kept (the initial snapshot is empty in this case).
+func (p *HTTPProvider) Watch(ctx context.Context, update func([]routing.Route)) error {
+	var (
+		ticker  = time.NewTicker(p.interval)
+		current = make([]routing.Route, 0)
+	)
+
+	defer ticker.Stop()
+
+	for {
+		if routes, err := p.fetch(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			p.log.Error("Failed to fetch the routes, previous routes are kept", zap.String("url", p.url), zap.Error(err))
+		} else {
+			current = routes
+		}
+
+		update(current)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetch requests the routes document and parses it.
+func (p *HTTPProvider) fetch(ctx context.Context) ([]routing.Route, error) {
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, p.url, http.NoBody)
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, respErr := p.client.Do(req)
+	if respErr != nil {
+		return nil, respErr
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	data, readErr := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read the response: %w", readErr)
+	}
+
+	return parse(data, FormatJSON, docker.RouteSourceHTTP)
+}
@@ -0,0 +1,95 @@
+package routesfile_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routesfile"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestHTTPProvider_Watch(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			_, _ = w.Write([]byte(`{"routes": [{"hostname": "foo", "url": "http://foo:1"}]}`))
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError) // the previous routes must be kept
+		default:
+			_, _ = w.Write([]byte(`{"routes": [{"hostname": "bar", "url": "http://bar:1"}]}`))
+		}
+	}))
+
+	defer srv.Close()
+
+	var updates = make(chan []routing.Route, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var p = routesfile.NewHTTPProvider(zap.NewNop(), srv.URL, 10*time.Millisecond)
+
+	assert.Equal(t, "http", p.Name())
+
+	go func() { _ = p.Watch(ctx, func(r []routing.Route) { updates <- r }) }()
+
+	var next = func() []routing.Route {
+		select {
+		case r := <-updates:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+
+			return nil
+		}
+	}
+
+	var first = next()
+
+	if assert.Len(t, first, 1) {
+		assert.Equal(t, "foo", first[0].Hostname)
+		assert.Equal(t, "http:foo", first[0].TargetID)
+		assert.Equal(t, docker.RouteSourceHTTP, first[0].Options.Source)
+	}
+
+	assert.Equal(t, first, next()) // failed request
+	assert.Equal(t, "bar", next()[0].Hostname)
+}
+
+func TestHTTPProvider_WatchUnavailable(t *testing.T) {
+	t.Parallel()
+
+	var srv = httptest.NewServer(http.NotFoundHandler())
+
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var updates = make(chan []routing.Route, 1)
+
+	go func() {
+		_ = routesfile.NewHTTPProvider(zap.NewNop(), srv.URL, time.Hour).Watch(ctx, func(r []routing.Route) {
+			updates <- r
+		})
+	}()
+
+	select {
+	case r := <-updates:
+		assert.Empty(t, r) // the initial (empty) snapshot is sent anyway
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
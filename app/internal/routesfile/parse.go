@@ -0,0 +1,280 @@
+package routesfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/net/http/httpguts"
+	"gopkg.in/yaml.v3"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+// Format is a routes file format.
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+	FormatJSON Format = "json" // parsed as YAML (JSON is a subset of YAML)
+)
+
+// FormatFromPath detects the routes file format using the file extension.
+func FormatFromPath(path string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yml", ".yaml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported routes file extension %q (.yml, .yaml, .toml or .json expected)", ext)
+	}
+}
+
+type (
+	// document is the routes file structure.
+	document struct {
+		Routes []entry `yaml:"routes" toml:"routes"` // the JSON format is decoded using the YAML decoder
+	}
+
+	// entry is a single route in the routes file.
+	entry struct {
+		Hostname     string            `yaml:"hostname"      toml:"hostname"`
+		URL          string            `yaml:"url"           toml:"url"`
+		PreserveHost bool              `yaml:"preserve_host" toml:"preserve_host"`
+		Headers      map[string]string `yaml:"headers"       toml:"headers"`
+	}
+)
+
+// Load reads and parses the routes file. The file format is detected using the file extension.
+func Load(path string) ([]routing.Route, error) {
+	format, formatErr := FormatFromPath(path)
+	if formatErr != nil {
+		return nil, formatErr
+	}
+
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read the routes file: %w", readErr)
+	}
+
+	return Parse(data, format)
+}
+
+// Parse parses and validates the routes file content. All validation errors are returned at once (joined), each of
+// them points to the invalid entry (and the line number, if possible).
+func Parse(data []byte, format Format) ([]routing.Route, error) {
+	return parse(data, format, docker.RouteSourceFile)
+}
+
+// parse parses the routes document, and marks the routes with the given source.
+func parse(data []byte, format Format, source docker.RouteSource) ([]routing.Route, error) {
+	var (
+		entries []entry
+		lines   []int // line numbers of the entries (0 = unknown)
+	)
+
+	switch format {
+	case FormatYAML, FormatJSON:
+		var doc yaml.Node
+
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+
+		var dec = yaml.NewDecoder(bytes.NewReader(data))
+
+		dec.KnownFields(true) // reject typos in the keys
+
+		var parsed document
+
+		if err := dec.Decode(&parsed); err != nil && !errors.Is(err, io.EOF) { // io.EOF means an empty file
+			return nil, fmt.Errorf("invalid routes file: %w", err)
+		}
+
+		entries, lines = parsed.Routes, yamlEntryLines(&doc)
+
+	case FormatTOML:
+		var parsed document
+
+		md, err := toml.Decode(string(data), &parsed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+
+		if undecoded := md.Undecoded(); len(undecoded) > 0 { // reject typos in the keys
+			var keys = make([]string, len(undecoded))
+
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+
+			return nil, fmt.Errorf("invalid routes file: unknown keys: %s", strings.Join(keys, ", "))
+		}
+
+		entries = parsed.Routes
+
+	default:
+		return nil, fmt.Errorf("unsupported routes file format: %s", format)
+	}
+
+	return validate(entries, lines, source)
+}
+
+// yamlEntryLines returns the line numbers of the route entries in the YAML document.
+func yamlEntryLines(doc *yaml.Node) []int {
+	if doc == nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var root = doc.Content[0]
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "routes" && root.Content[i+1].Kind == yaml.SequenceNode {
+			var lines = make([]int, len(root.Content[i+1].Content))
+
+			for j, item := range root.Content[i+1].Content {
+				lines[j] = item.Line
+			}
+
+			return lines
+		}
+	}
+
+	return nil
+}
+
+// validate converts the entries to the routes. All validation errors are joined.
+func validate(entries []entry, lines []int, source docker.RouteSource) ([]routing.Route, error) {
+	var (
+		routes = make([]routing.Route, 0, len(entries))
+		seen   = make(map[string]int, len(entries)) // map[hostname]entry_index
+		errs   []error
+	)
+
+	for i, e := range entries {
+		var where = fmt.Sprintf("routes[%d]", i)
+
+		if i < len(lines) && lines[i] > 0 {
+			where += fmt.Sprintf(" (line %d)", lines[i])
+		}
+
+		var route, entryErrs = validateEntry(e, source)
+
+		if route.Hostname != "" {
+			if prev, dup := seen[route.Hostname]; dup {
+				entryErrs = append(entryErrs, fmt.Errorf("hostname: %q is already defined in routes[%d]", route.Hostname, prev))
+			} else {
+				seen[route.Hostname] = i
+			}
+		}
+
+		for _, err := range entryErrs {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+
+		routes = append(routes, route)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return routes, nil
+}
+
+// NewRoute validates the route properties and creates the route with the given source. All validation errors are
+// joined.
+func NewRoute(
+	source docker.RouteSource,
+	hostname, rawURL string,
+	preserveHost bool,
+	headers map[string]string,
+) (routing.Route, error) {
+	var e = entry{Hostname: hostname, URL: rawURL, PreserveHost: preserveHost, Headers: headers}
+
+	route, errs := validateEntry(e, source)
+	if len(errs) > 0 {
+		return routing.Route{}, errors.Join(errs...)
+	}
+
+	return route, nil
+}
+
+// validateEntry converts the entry to the route. The hostname (and the target ID) is set only if it's valid.
+func validateEntry(e entry, source docker.RouteSource) (routing.Route, []error) { //nolint:funlen
+	var (
+		route = routing.Route{Options: docker.RouteOptions{Source: source, PreserveHost: e.PreserveHost}}
+		errs  []error
+		fail  = func(field, format string, args ...any) {
+			errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	)
+
+	// validate the hostname
+	var hostname = strings.ToLower(strings.TrimSpace(e.Hostname))
+
+	hostname = strings.TrimSuffix(hostname, ".indocker.app")
+
+	switch {
+	case hostname == "":
+		fail("hostname", "is required")
+	case !docker.IsValidHostname(hostname):
+		fail("hostname", "%q is not a valid hostname", e.Hostname)
+	case docker.IsReservedHostname(hostname):
+		fail("hostname", "%q is reserved by indocker", hostname)
+	default:
+		route.Hostname, route.TargetID = hostname, string(source)+":"+hostname
+	}
+
+	// validate the upstream URL
+	if raw := strings.TrimSpace(e.URL); raw == "" {
+		fail("url", "is required")
+	} else if u, err := url.Parse(raw); err != nil {
+		fail("url", "%q is not a valid URL: %s", raw, errors.Unwrap(err))
+	} else {
+		switch {
+		case !docker.IsSupportedScheme(u.Scheme):
+			fail("url", "scheme %q is not supported (http, https, h2c, grpc or grpcs expected)", u.Scheme)
+		case u.Host == "":
+			fail("url", "%q has no host", raw)
+		case u.Path != "" && u.Path != "/":
+			fail("url", "%q has a path, which is not supported", raw)
+		case u.RawQuery != "" || u.Fragment != "" || u.User != nil:
+			fail("url", "%q must not contain the user info, query or fragment", raw)
+		default:
+			route.URL = url.URL{Scheme: u.Scheme, Host: u.Host}
+		}
+	}
+
+	// validate the headers
+	if len(e.Headers) > 0 {
+		route.Options.Headers = make(map[string]string, len(e.Headers))
+
+		for _, name := range slices.Sorted(maps.Keys(e.Headers)) { // sorted to keep the errors order stable
+			var value = e.Headers[name]
+
+			if !httpguts.ValidHeaderFieldName(name) {
+				fail("headers", "%q is not a valid header name", name)
+			} else if !httpguts.ValidHeaderFieldValue(value) {
+				fail("headers", "the %s header value is invalid", name)
+			} else {
+				route.Options.Headers[name] = value
+			}
+		}
+	}
+
+	return route, errs
+}
@@ -0,0 +1,164 @@
+package routesfile_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routesfile"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		giveData    string
+		giveFormat  routesfile.Format
+		wantRoutes  []routing.Route
+		wantErrorIs []string // substrings of the error message
+	}{
+		"yaml": {
+			giveData: `
+routes:
+  - hostname: Dev.indocker.app
+    url: http://host.docker.internal:3000
+    preserve_host: true
+    headers: {X-Foo: bar}
+  - hostname: vm
+    url: https://10.0.0.1/
+`,
+			giveFormat: routesfile.FormatYAML,
+			wantRoutes: []routing.Route{
+				{
+					Hostname: "dev",
+					TargetID: "file:dev",
+					URL:      url.URL{Scheme: "http", Host: "host.docker.internal:3000"},
+					Options: docker.RouteOptions{
+						Source:       docker.RouteSourceFile,
+						PreserveHost: true,
+						Headers:      map[string]string{"X-Foo": "bar"},
+					},
+				},
+				{
+					Hostname: "vm",
+					TargetID: "file:vm",
+					URL:      url.URL{Scheme: "https", Host: "10.0.0.1"},
+					Options:  docker.RouteOptions{Source: docker.RouteSourceFile},
+				},
+			},
+		},
+		"toml": {
+			giveData: `
+[[routes]]
+hostname = "dev"
+url = "http://192.168.1.10:8080"
+`,
+			giveFormat: routesfile.FormatTOML,
+			wantRoutes: []routing.Route{{
+				Hostname: "dev",
+				TargetID: "file:dev",
+				URL:      url.URL{Scheme: "http", Host: "192.168.1.10:8080"},
+				Options:  docker.RouteOptions{Source: docker.RouteSourceFile},
+			}},
+		},
+		"json": {
+			giveData:   `{"routes": [{"hostname": "dev", "url": "http://192.168.1.10:8080", "headers": {"X-Foo": "bar"}}]}`,
+			giveFormat: routesfile.FormatJSON,
+			wantRoutes: []routing.Route{{
+				Hostname: "dev",
+				TargetID: "file:dev",
+				URL:      url.URL{Scheme: "http", Host: "192.168.1.10:8080"},
+				Options: docker.RouteOptions{
+					Source:  docker.RouteSourceFile,
+					Headers: map[string]string{"X-Foo": "bar"},
+				},
+			}},
+		},
+		"empty yaml": {
+			giveData:   "",
+			giveFormat: routesfile.FormatYAML,
+			wantRoutes: []routing.Route{},
+		},
+		"invalid entries (yaml)": {
+			giveData: `
+routes:
+  - hostname: dev
+    url: ftp://foo
+  - hostname: dev
+    url: http://foo/bar
+  - hostname: monitor
+  - hostname: "bad_host"
+    url: http://foo
+    headers: {"X Foo": bar}
+`,
+			giveFormat: routesfile.FormatYAML,
+			wantErrorIs: []string{
+				`routes[0] (line 3): url: scheme "ftp" is not supported`,
+				`routes[1] (line 5): hostname: "dev" is already defined in routes[0]`,
+				`routes[1] (line 5): url: "http://foo/bar" has a path`,
+				`routes[2] (line 7): hostname: "monitor" is reserved`,
+				`routes[2] (line 7): url: is required`,
+				`routes[3] (line 8): hostname: "bad_host" is not a valid hostname`,
+				`routes[3] (line 8): headers: "X Foo" is not a valid header name`,
+			},
+		},
+		"unknown key (yaml)": {
+			giveData:    "routes:\n  - hostname: dev\n    ulr: http://foo\n",
+			giveFormat:  routesfile.FormatYAML,
+			wantErrorIs: []string{"field ulr not found"},
+		},
+		"unknown key (toml)": {
+			giveData:    "[[routes]]\nhostname = \"dev\"\nulr = \"http://foo\"\n",
+			giveFormat:  routesfile.FormatTOML,
+			wantErrorIs: []string{"unknown keys: routes.ulr"},
+		},
+		"invalid entry (toml)": {
+			giveData:    "[[routes]]\nurl = \"http://foo\"\n",
+			giveFormat:  routesfile.FormatTOML,
+			wantErrorIs: []string{"routes[0]: hostname: is required"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			routes, err := routesfile.Parse([]byte(tt.giveData), tt.giveFormat)
+
+			if len(tt.wantErrorIs) > 0 {
+				require.Error(t, err)
+
+				for _, want := range tt.wantErrorIs {
+					assert.Contains(t, err.Error(), want)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRoutes, routes)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+
+	var path = filepath.Join(dir, "routes.yml")
+
+	require.NoError(t, os.WriteFile(path, []byte("routes: [{hostname: dev, url: 'http://foo:1'}]"), 0o600))
+
+	routes, err := routesfile.Load(path)
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	assert.Equal(t, "dev", routes[0].Hostname)
+
+	_, err = routesfile.Load(filepath.Join(dir, "routes.ini"))
+	assert.ErrorContains(t, err, "unsupported routes file extension")
+}
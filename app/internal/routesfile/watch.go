@@ -0,0 +1,109 @@
+package routesfile
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+// reloadDelay is used to debounce the file system events (editors may write the file in several steps).
+const reloadDelay = 100 * time.Millisecond
+
+// FileProvider provides the routes, defined in the routes file. The file is watched for changes and reloaded
+// automatically.
+type FileProvider struct {
+	log  *zap.Logger
+	path string
+}
+
+var _ routing.Provider = (*FileProvider)(nil) // verify interface implementation
+
+// NewFileProvider creates a new routes file provider.
+func NewFileProvider(log *zap.Logger, path string) *FileProvider {
+	return &FileProvider{log: log, path: path}
+}
+
+// Name returns the provider name.
+func (*FileProvider) Name() string { return string(docker.RouteSourceFile) }
+
+// Watch loads the routes file and watches it for changes. If the file can't be loaded initially, the error is
+// returned. If the reloaded file is invalid, the error is logged and the previously loaded routes are kept.
+func (p *FileProvider) Watch(ctx context.Context, update func([]routing.Route)) error { //nolint:funlen
+	absPath, absErr := filepath.Abs(p.path)
+	if absErr != nil {
+		return fmt.Errorf("failed to resolve the routes file path: %w", absErr)
+	}
+
+	routes, loadErr := Load(absPath)
+	if loadErr != nil {
+		return fmt.Errorf("failed to load the routes file %s: %w", p.path, loadErr)
+	}
+
+	update(routes)
+
+	p.log.Info("Routes file loaded", zap.String("path", absPath), zap.Int("routes", len(routes)))
+
+	watcher, watcherErr := fsnotify.NewWatcher()
+	if watcherErr != nil {
+		return fmt.Errorf("failed to create the file watcher: %w", watcherErr)
+	}
+
+	defer func() { _ = watcher.Close() }()
+
+	// the directory is watched (not the file itself), because editors often replace the file instead of writing
+	// to it, and the file watch is lost in this case
+	if err := watcher.Add(filepath.Dir(absPath)); err != nil {
+		return fmt.Errorf("failed to watch the routes file directory: %w", err)
+	}
+
+	var reload = time.NewTimer(reloadDelay)
+
+	reload.Stop()
+
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if filepath.Clean(event.Name) == absPath && !event.Has(fsnotify.Chmod) {
+				reload.Reset(reloadDelay) // debounce
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			p.log.Warn("Routes file watcher error", zap.Error(err))
+
+		case <-reload.C:
+			reloaded, err := Load(absPath)
+			if err != nil {
+				p.log.Error("Failed to reload the routes file, previous routes are kept",
+					zap.String("path", absPath),
+					zap.Error(err),
+				)
+
+				continue
+			}
+
+			update(reloaded)
+
+			p.log.Info("Routes file reloaded", zap.String("path", absPath), zap.Int("routes", len(reloaded)))
+		}
+	}
+}
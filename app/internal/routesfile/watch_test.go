@@ -0,0 +1,68 @@
+package routesfile_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/routesfile"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestFileProvider_Watch(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "routes.yml")
+
+	require.NoError(t, os.WriteFile(path, []byte("routes: [{hostname: foo, url: 'http://foo:1'}]"), 0o600))
+
+	var updates = make(chan []routing.Route, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var p = routesfile.NewFileProvider(zap.NewNop(), path)
+
+	assert.Equal(t, "file", p.Name())
+
+	go func() { _ = p.Watch(ctx, func(r []routing.Route) { updates <- r }) }()
+
+	var next = func() []routing.Route {
+		select {
+		case r := <-updates:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+
+			return nil
+		}
+	}
+
+	assert.Equal(t, "foo", next()[0].Hostname) // initial load
+
+	// invalid content is ignored
+	require.NoError(t, os.WriteFile(path, []byte("routes: [{hostname: foo}]"), 0o600))
+
+	// the valid one is applied
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte("routes: [{hostname: bar, url: 'http://bar:1'}]"), 0o600))
+
+	assert.Equal(t, "bar", next()[0].Hostname)
+}
+
+func TestFileProvider_WatchInvalidFile(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "routes.toml")
+
+	require.NoError(t, os.WriteFile(path, []byte("[[routes]]\nhostname = \"\""), 0o600))
+
+	var err = routesfile.NewFileProvider(zap.NewNop(), path).Watch(context.Background(), func([]routing.Route) {})
+	assert.ErrorContains(t, err, "hostname: is required")
+}
@@ -0,0 +1,302 @@
+// Package routestore keeps the routes, added at runtime (using the monitor API). The routes may expire, and may be
+// persisted to the state file to survive restarts.
+package routestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routesfile"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+type (
+	// Entry is a runtime route.
+	Entry struct {
+		Hostname     string            `json:"hostname"`
+		URL          string            `json:"url"`
+		PreserveHost bool              `json:"preserve_host,omitempty"`
+		Headers      map[string]string `json:"headers,omitempty"`
+		ExpiresAt    *time.Time        `json:"expires_at,omitempty"` // nil = never expires
+	}
+
+	// Store keeps the runtime routes, and provides them to the routes aggregator.
+	Store struct {
+		log  *zap.Logger
+		path string // path to the state file (empty = do not persist)
+		now  func() time.Time
+
+		mu      sync.Mutex
+		entries map[string]Entry // map[hostname]Entry
+		mem     *routing.MemoryProvider
+	}
+
+	// Option allows to change the [Store] options.
+	Option func(*Store)
+
+	// state is the state file structure.
+	state struct {
+		Routes []Entry `json:"routes"`
+	}
+)
+
+var (
+	// ErrInvalidRoute is returned when the route properties are invalid.
+	ErrInvalidRoute = errors.New("invalid route")
+
+	// ErrRouteExists is returned when the route with the same hostname already exists.
+	ErrRouteExists = errors.New("route already exists")
+
+	// ErrRouteNotFound is returned when the route does not exist.
+	ErrRouteNotFound = errors.New("route not found")
+)
+
+var _ routing.Provider = (*Store)(nil) // verify interface implementation
+
+// WithStateFile sets the path to the state file, where the routes are persisted.
+func WithStateFile(path string) Option { return func(s *Store) { s.path = path } }
+
+// WithClock sets the function, which returns the current time (useful for testing).
+func WithClock(now func() time.Time) Option { return func(s *Store) { s.now = now } }
+
+// New creates a new routes store. If the state file is set and exists, the routes are loaded from it (expired routes
+// are skipped).
+func New(log *zap.Logger, opts ...Option) (*Store, error) {
+	var s = Store{
+		log:     log,
+		now:     time.Now,
+		entries: make(map[string]Entry),
+		mem:     routing.NewMemoryProvider(string(docker.RouteSourceAPI)),
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Name returns the provider name.
+func (*Store) Name() string { return string(docker.RouteSourceAPI) }
+
+// expireInterval is how often the expired routes are removed.
+const expireInterval = time.Second
+
+// Watch implements the [routing.Provider] interface. It also removes the expired routes.
+func (s *Store) Watch(ctx context.Context, update func([]routing.Route)) error {
+	go func() {
+		var ticker = time.NewTicker(expireInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.expire()
+			}
+		}
+	}()
+
+	return s.mem.Watch(ctx, update)
+}
+
+// Create adds a new route. If the route with the same hostname already exists, [ErrRouteExists] is returned.
+func (s *Store) Create(e Entry) (Entry, error) { return s.set(e, false) }
+
+// Put adds a new route, or replaces the existing one.
+func (s *Store) Put(e Entry) (Entry, error) { return s.set(e, true) }
+
+// Delete removes the route by its hostname. If the route does not exist, [ErrRouteNotFound] is returned.
+func (s *Store) Delete(hostname string) (Entry, error) {
+	hostname = normalizeHostname(hostname)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[hostname]
+	if !ok {
+		return Entry{}, fmt.Errorf("%w: %s", ErrRouteNotFound, hostname)
+	}
+
+	var entries = maps.Clone(s.entries)
+
+	delete(entries, hostname)
+
+	if err := s.persist(entries); err != nil {
+		return Entry{}, err // the route is kept, since the state file is not changed
+	}
+
+	s.entries = entries
+	s.mem.Delete(hostname)
+
+	return e, nil
+}
+
+// Entries returns all routes, sorted by the hostname.
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries = slices.Collect(maps.Values(s.entries))
+
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Hostname, b.Hostname) })
+
+	return entries
+}
+
+// set validates and stores the route. The mu must not be locked by the caller.
+func (s *Store) set(e Entry, replace bool) (Entry, error) {
+	route, err := routesfile.NewRoute(docker.RouteSourceAPI, e.Hostname, e.URL, e.PreserveHost, e.Headers)
+	if err != nil {
+		return Entry{}, fmt.Errorf("%w: %w", ErrInvalidRoute, err)
+	}
+
+	if e.ExpiresAt != nil && !e.ExpiresAt.After(s.now()) {
+		return Entry{}, fmt.Errorf("%w: the route is already expired", ErrInvalidRoute)
+	}
+
+	e.Hostname, e.URL = route.Hostname, route.URL.String() // normalized
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[e.Hostname]; exists && !replace {
+		return Entry{}, fmt.Errorf("%w: %s", ErrRouteExists, e.Hostname)
+	}
+
+	var entries = maps.Clone(s.entries)
+
+	entries[e.Hostname] = e
+
+	if err = s.persist(entries); err != nil {
+		return Entry{}, err // the route is not applied, since the state file is not changed
+	}
+
+	s.entries = entries
+	s.mem.Set(route)
+
+	return e, nil
+}
+
+// expire removes the expired routes.
+func (s *Store) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var now, expired = s.now(), false
+
+	for hostname, e := range s.entries {
+		if e.ExpiresAt != nil && !e.ExpiresAt.After(now) {
+			delete(s.entries, hostname)
+			s.mem.Delete(hostname)
+
+			expired = true
+
+			s.log.Info("Route expired", zap.String("hostname", hostname))
+		}
+	}
+
+	if expired {
+		if err := s.persist(s.entries); err != nil {
+			s.log.Error("Failed to save the routes state", zap.Error(err))
+		}
+	}
+}
+
+// load reads the routes from the state file (if it exists). Invalid and expired routes are skipped.
+func (s *Store) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, readErr := os.ReadFile(s.path)
+	if readErr != nil {
+		if errors.Is(readErr, os.ErrNotExist) {
+			return nil // nothing to load
+		}
+
+		return fmt.Errorf("failed to read the routes state file: %w", readErr)
+	}
+
+	var st state
+
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("invalid routes state file %s: %w", s.path, err)
+	}
+
+	var now = s.now()
+
+	for _, e := range st.Routes {
+		if e.ExpiresAt != nil && !e.ExpiresAt.After(now) {
+			continue
+		}
+
+		route, err := routesfile.NewRoute(docker.RouteSourceAPI, e.Hostname, e.URL, e.PreserveHost, e.Headers)
+		if err != nil {
+			s.log.Warn("Invalid route in the state file skipped", zap.String("hostname", e.Hostname), zap.Error(err))
+
+			continue
+		}
+
+		e.Hostname = route.Hostname
+
+		s.entries[e.Hostname] = e
+		s.mem.Set(route)
+	}
+
+	return nil
+}
+
+// persist writes the routes to the state file (atomically). The mu must be locked by the caller.
+func (s *Store) persist(entries map[string]Entry) error {
+	if s.path == "" {
+		return nil
+	}
+
+	var st = state{Routes: slices.SortedFunc(maps.Values(entries), func(a, b Entry) int {
+		return strings.Compare(a.Hostname, b.Hostname)
+	})}
+
+	if st.Routes == nil {
+		st.Routes = make([]Entry, 0)
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var tmp = filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+
+	if err = os.WriteFile(tmp, data, 0o600); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to save the routes state: %w", err)
+	}
+
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save the routes state: %w", err)
+	}
+
+	return nil
+}
+
+// normalizeHostname converts the hostname to the form, used as the store key.
+func normalizeHostname(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".indocker.app")
+}
@@ -0,0 +1,176 @@
+package routestore_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routestore"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestStore_CreatePutDelete(t *testing.T) {
+	t.Parallel()
+
+	s, err := routestore.New(zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, "api", s.Name())
+
+	saved, err := s.Create(routestore.Entry{Hostname: "Vite.indocker.app", URL: "http://127.0.0.1:5173/"})
+	require.NoError(t, err)
+	assert.Equal(t, routestore.Entry{Hostname: "vite", URL: "http://127.0.0.1:5173"}, saved)
+
+	_, err = s.Create(routestore.Entry{Hostname: "vite", URL: "http://127.0.0.1:5174"})
+	assert.ErrorIs(t, err, routestore.ErrRouteExists)
+
+	saved, err = s.Put(routestore.Entry{Hostname: "vite", URL: "http://127.0.0.1:5174"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:5174", saved.URL)
+
+	_, err = s.Put(routestore.Entry{Hostname: "monitor", URL: "ftp://foo"})
+	assert.ErrorIs(t, err, routestore.ErrInvalidRoute)
+	assert.ErrorContains(t, err, "reserved")
+	assert.ErrorContains(t, err, "not supported")
+
+	assert.Len(t, s.Entries(), 1)
+
+	_, err = s.Delete("vite")
+	require.NoError(t, err)
+
+	_, err = s.Delete("vite")
+	assert.ErrorIs(t, err, routestore.ErrRouteNotFound)
+
+	assert.Empty(t, s.Entries())
+}
+
+func TestStore_Persistence(t *testing.T) {
+	t.Parallel()
+
+	var (
+		path  = filepath.Join(t.TempDir(), "routes.json")
+		now   = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		soon  = now.Add(time.Minute)
+		clock = func() time.Time { return now }
+	)
+
+	s, err := routestore.New(zap.NewNop(), routestore.WithStateFile(path), routestore.WithClock(clock))
+	require.NoError(t, err)
+
+	_, err = s.Create(routestore.Entry{Hostname: "foo", URL: "http://foo:1", Headers: map[string]string{"X-Foo": "1"}})
+	require.NoError(t, err)
+	_, err = s.Create(routestore.Entry{Hostname: "bar", URL: "http://bar:1", ExpiresAt: &soon})
+	require.NoError(t, err)
+
+	require.FileExists(t, path)
+
+	// the routes survive the restart
+	restored, err := routestore.New(zap.NewNop(), routestore.WithStateFile(path), routestore.WithClock(clock))
+	require.NoError(t, err)
+	assert.Equal(t, s.Entries(), restored.Entries())
+
+	// expired routes are skipped
+	var later = func() time.Time { return soon.Add(time.Second) }
+
+	restored, err = routestore.New(zap.NewNop(), routestore.WithStateFile(path), routestore.WithClock(later))
+	require.NoError(t, err)
+
+	if entries := restored.Entries(); assert.Len(t, entries, 1) {
+		assert.Equal(t, "foo", entries[0].Hostname)
+	}
+
+	// broken state file
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, err = routestore.New(zap.NewNop(), routestore.WithStateFile(path))
+	assert.ErrorContains(t, err, "invalid routes state file")
+}
+
+func TestStore_PersistenceFailure(t *testing.T) {
+	t.Parallel()
+
+	var dir = filepath.Join(t.TempDir(), "state")
+
+	require.NoError(t, os.Mkdir(dir, 0o700))
+
+	s, err := routestore.New(zap.NewNop(), routestore.WithStateFile(filepath.Join(dir, "routes.json")))
+	require.NoError(t, err)
+
+	_, err = s.Create(routestore.Entry{Hostname: "foo", URL: "http://foo:1"})
+	require.NoError(t, err)
+
+	require.NoError(t, os.RemoveAll(dir)) // the state file can't be written anymore
+
+	_, err = s.Create(routestore.Entry{Hostname: "bar", URL: "http://bar:1"})
+	assert.ErrorContains(t, err, "failed to save the routes state")
+
+	_, err = s.Put(routestore.Entry{Hostname: "foo", URL: "http://foo:2"})
+	assert.ErrorContains(t, err, "failed to save the routes state")
+
+	_, err = s.Delete("foo")
+	assert.ErrorContains(t, err, "failed to save the routes state")
+
+	// nothing is changed
+	assert.Equal(t, []routestore.Entry{{Hostname: "foo", URL: "http://foo:1"}}, s.Entries())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var updates = make(chan []routing.Route, 1)
+
+	go func() { _ = s.Watch(ctx, func(r []routing.Route) { updates <- r }) }()
+
+	select {
+	case routes := <-updates:
+		if assert.Len(t, routes, 1) {
+			assert.Equal(t, "foo", routes[0].Hostname)
+			assert.Equal(t, "http://foo:1", routes[0].URL.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestStore_Watch(t *testing.T) {
+	t.Parallel()
+
+	var expiresAt = time.Now().Add(time.Second)
+
+	s, err := routestore.New(zap.NewNop())
+	require.NoError(t, err)
+
+	_, err = s.Create(routestore.Entry{Hostname: "foo", URL: "http://foo:1", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var updates = make(chan []routing.Route, 10)
+
+	go func() { _ = s.Watch(ctx, func(r []routing.Route) { updates <- r }) }()
+
+	var next = func() []routing.Route {
+		select {
+		case r := <-updates:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+
+			return nil
+		}
+	}
+
+	if routes := next(); assert.Len(t, routes, 1) {
+		assert.Equal(t, "api:foo", routes[0].TargetID)
+		assert.Equal(t, docker.RouteSourceAPI, routes[0].Options.Source)
+	}
+
+	assert.Empty(t, next()) // expired
+}
@@ -0,0 +1,338 @@
+package routing
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type (
+	// Aggregator merges the routes from all registered providers into a single routing table. If the same hostname
+	// is provided by several providers, the provider with the highest priority wins (providers with the same
+	// priority are merged, and the traffic is balanced between their targets).
+	//
+	// Aggregator implements the same interfaces as the [docker.State] does, so it can be used instead of it.
+	Aggregator struct {
+		log *zap.Logger
+
+		providersMu sync.Mutex
+		providers   []*registeredProvider
+		ready       chan struct{} // closed when all providers have sent the initial snapshot
+		readyOnce   sync.Once
+
+		routesMu  sync.Mutex
+		routes    docker.RoutesMap               // map[hostname]map[target_id]url.URL
+		options   map[string]docker.RouteOptions // map[target_id]RouteOptions
+		conflicts []docker.RouteConflict         // the route conflicts, reported by the providers
+		removed   map[string]time.Time           // when the hostnames were removed, map[hostname]time
+
+		subsMu sync.Mutex
+		subs   map[chan docker.RoutesMap]chan struct{} // map[subscription]stop_channel
+	}
+
+	registeredProvider struct {
+		provider Provider
+		priority int
+		routes   []Route // the last snapshot
+		synced   bool    // true if the initial snapshot has been received
+	}
+)
+
+var (
+	_ docker.RoutingURLResolver       = (*Aggregator)(nil) // verify interface implementation
+	_ docker.AllContainerURLsResolver = (*Aggregator)(nil) // --//--
+	_ docker.RoutingUpdateSubscriber  = (*Aggregator)(nil) // --//--
+	_ docker.RouteOptionsResolver     = (*Aggregator)(nil) // --//--
+	_ docker.RouteConflictsResolver   = (*Aggregator)(nil) // --//--
+	_ docker.RemovedRouteResolver     = (*Aggregator)(nil) // --//--
+)
+
+// NewAggregator creates a new (empty) routes aggregator.
+func NewAggregator(log *zap.Logger) *Aggregator {
+	return &Aggregator{
+		log:     log,
+		ready:   make(chan struct{}),
+		routes:  make(docker.RoutesMap),
+		options: make(map[string]docker.RouteOptions),
+		removed: make(map[string]time.Time),
+		subs:    make(map[chan docker.RoutesMap]chan struct{}),
+	}
+}
+
+// Add registers the provider with the given priority (the higher, the more important). Providers must be added
+// before the [Aggregator.Run] call.
+func (a *Aggregator) Add(p Provider, priority int) *Aggregator {
+	a.providersMu.Lock()
+	a.providers = append(a.providers, &registeredProvider{provider: p, priority: priority})
+	a.providersMu.Unlock()
+
+	return a
+}
+
+// Run runs all registered providers and blocks until the context is canceled. If any provider fails, all others are
+// stopped, and the error is returned.
+func (a *Aggregator) Run(ctx context.Context) error {
+	a.providersMu.Lock()
+	var providers = slices.Clone(a.providers)
+	a.providersMu.Unlock()
+
+	if len(providers) == 0 {
+		a.readyOnce.Do(func() { close(a.ready) })
+	}
+
+	var eg, egCtx = errgroup.WithContext(ctx)
+
+	for _, rp := range providers {
+		eg.Go(func() error {
+			if err := rp.provider.Watch(egCtx, func(routes []Route) { a.update(egCtx, rp, routes) }); err != nil {
+				return fmt.Errorf("%s routes provider: %w", rp.provider.Name(), err)
+			}
+
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+// Ready returns a channel, which is closed when all providers have sent their initial snapshots.
+func (a *Aggregator) Ready() <-chan struct{} { return a.ready }
+
+// update stores the provider snapshot, rebuilds the routing table and notifies the subscribers.
+func (a *Aggregator) update(ctx context.Context, rp *registeredProvider, routes []Route) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock() // keeps the updates order
+
+	rp.routes, rp.synced = slices.Clone(routes), true
+
+	var allSynced = true
+
+	for _, p := range a.providers {
+		allSynced = allSynced && p.synced
+	}
+
+	var (
+		newRoutes, newOptions = a.merge()
+		newConflicts          = a.collectConflicts()
+	)
+
+	a.routesMu.Lock()
+	var updated = !reflect.DeepEqual(a.routes, newRoutes) || !reflect.DeepEqual(a.options, newOptions) ||
+		!reflect.DeepEqual(a.conflicts, newConflicts) // conflicts are a part of the routing info for the subscribers
+	a.trackRemoved(newRoutes)
+	a.routes, a.options, a.conflicts = newRoutes, newOptions, newConflicts
+	a.routesMu.Unlock()
+
+	if allSynced {
+		a.readyOnce.Do(func() { close(a.ready) })
+	}
+
+	if updated {
+		a.log.Debug("Routes updated", zap.String("provider", rp.provider.Name()), zap.Int("hostnames", len(newRoutes)))
+
+		a.notifySubscribers(ctx, newRoutes)
+	}
+}
+
+// merge builds the routing table using the last snapshots of all providers. The providersMu must be locked by the
+// caller.
+func (a *Aggregator) merge() (docker.RoutesMap, map[string]docker.RouteOptions) {
+	var (
+		routes    = make(docker.RoutesMap)
+		options   = make(map[string]docker.RouteOptions)
+		priority  = make(map[string]int)      // map[hostname]the_winning_priority
+		providers = slices.Clone(a.providers) // to sort without affecting the registration order
+	)
+
+	// the highest priority first (provider names are used to make the order deterministic)
+	slices.SortStableFunc(providers, func(x, y *registeredProvider) int {
+		if c := cmp.Compare(y.priority, x.priority); c != 0 {
+			return c
+		}
+
+		return strings.Compare(x.provider.Name(), y.provider.Name())
+	})
+
+	for _, p := range providers {
+		for _, r := range p.routes {
+			if r.Hostname == "" {
+				continue
+			}
+
+			if winning, taken := priority[r.Hostname]; taken && winning > p.priority {
+				continue // the hostname is already taken by the provider with the higher priority
+			}
+
+			priority[r.Hostname] = p.priority
+
+			if _, ok := routes[r.Hostname]; !ok {
+				routes[r.Hostname] = make(docker.ContainerMap)
+			}
+
+			var opts = r.Options
+
+			if opts.Source == "" {
+				opts.Source = docker.RouteSource(p.provider.Name())
+			}
+
+			routes[r.Hostname][r.TargetID], options[r.TargetID] = r.URL, opts
+		}
+	}
+
+	return routes, options
+}
+
+// removedRetention is how long the removed hostnames are remembered.
+const removedRetention = 10 * time.Minute
+
+// trackRemoved remembers when the hostnames, missing in the new routing table, were removed. The routesMu must be
+// locked by the caller.
+func (a *Aggregator) trackRemoved(newRoutes docker.RoutesMap) {
+	var now = time.Now()
+
+	for hostname, removedAt := range a.removed {
+		if _, back := newRoutes[hostname]; back || now.Sub(removedAt) > removedRetention {
+			delete(a.removed, hostname)
+		}
+	}
+
+	for hostname := range a.routes {
+		if _, ok := newRoutes[hostname]; !ok {
+			a.removed[hostname] = now
+		}
+	}
+}
+
+// RouteRemovedAt returns the time when the (previously routed) hostname was removed from the routing table. It
+// returns false if the hostname is routed now, or was never routed (or was removed long ago).
+func (a *Aggregator) RouteRemovedAt(hostname string) (time.Time, bool) {
+	hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".indocker.app")
+
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	t, ok := a.removed[hostname]
+
+	return t, ok
+}
+
+// notifySubscribers sends the routing to all subscribers (asynchronously).
+func (a *Aggregator) notifySubscribers(ctx context.Context, routes docker.RoutesMap) {
+	a.subsMu.Lock()
+	defer a.subsMu.Unlock()
+
+	for sub, stop := range a.subs {
+		go func(sub chan<- docker.RoutesMap, stop <-chan struct{}) {
+			select { // first, check if the subscription and context are still alive
+			case <-stop:
+			case <-ctx.Done():
+			default:
+				select { // then, notify the subscriber
+				case <-stop:
+				case <-ctx.Done():
+				case sub <- maps.Clone(routes):
+				}
+			}
+		}(sub, stop)
+	}
+}
+
+// SubscribeForRoutingUpdates returns a subscription channel and a stop function. The subscription channel receives
+// the routing table every time it's changed. The channel is closed when the stop function is called.
+func (a *Aggregator) SubscribeForRoutingUpdates() (<-chan docker.RoutesMap, func()) {
+	var ch, cancel = make(chan docker.RoutesMap, 1), make(chan struct{})
+
+	var stop = sync.OnceFunc(func() {
+		close(cancel)
+
+		a.subsMu.Lock()
+		delete(a.subs, ch)
+		a.subsMu.Unlock()
+
+		// empty the channel
+	emptyLoop:
+		for {
+			select {
+			case <-ch:
+			default:
+				close(ch)
+
+				break emptyLoop
+			}
+		}
+	})
+
+	a.subsMu.Lock()
+	a.subs[ch] = cancel
+	a.subsMu.Unlock()
+
+	return ch, stop
+}
+
+// URLToContainerByHostname returns the route targets for the hostname. It returns false if the hostname is not
+// routed.
+func (a *Aggregator) URLToContainerByHostname(hostname string) (docker.ContainerMap, bool) {
+	hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".indocker.app")
+
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	u, ok := a.routes[hostname]
+
+	return u, ok
+}
+
+// AllContainerURLs returns the whole routing table.
+func (a *Aggregator) AllContainerURLs() docker.RoutesMap {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	return maps.Clone(a.routes)
+}
+
+// RouteOptions returns the options of the route target with the given ID.
+func (a *Aggregator) RouteOptions(targetID string) (docker.RouteOptions, bool) {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	opts, ok := a.options[targetID]
+
+	return opts, ok
+}
+
+// RouteConflicts returns the route conflicts, reported by the providers (which implement the
+// [docker.RouteConflictsResolver] interface), sorted by the hostname.
+func (a *Aggregator) RouteConflicts() []docker.RouteConflict {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	return slices.Clone(a.conflicts)
+}
+
+// collectConflicts collects the route conflicts from the providers. The providersMu must be locked by the caller.
+func (a *Aggregator) collectConflicts() []docker.RouteConflict {
+	var conflicts = make([]docker.RouteConflict, 0)
+
+	for _, p := range a.providers {
+		if resolver, ok := p.provider.(docker.RouteConflictsResolver); ok {
+			conflicts = append(conflicts, resolver.RouteConflicts()...)
+		}
+	}
+
+	slices.SortStableFunc(conflicts, func(x, y docker.RouteConflict) int {
+		return strings.Compare(x.Hostname, y.Hostname)
+	})
+
+	return conflicts
+}
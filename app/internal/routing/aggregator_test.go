@@ -0,0 +1,172 @@
+package routing_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func route(hostname, targetID, host string) routing.Route {
+	return routing.Route{Hostname: hostname, TargetID: targetID, URL: url.URL{Scheme: "http", Host: host}}
+}
+
+// runAggregator runs the aggregator in background and waits until it's ready.
+func runAggregator(t *testing.T, a *routing.Aggregator) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() { _ = a.Run(ctx) }()
+
+	select {
+	case <-a.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestAggregator_Priority(t *testing.T) {
+	t.Parallel()
+
+	var (
+		low    = routing.NewMemoryProvider("low")
+		lowToo = routing.NewMemoryProvider("low2")
+		high   = routing.NewMemoryProvider("high")
+		agg    = routing.NewAggregator(zap.NewNop()).Add(low, 0).Add(lowToo, 0).Add(high, 10)
+	)
+
+	low.Set(route("foo", "low:foo", "low:1"))
+	low.Set(route("bar", "low:bar", "low:2"))
+	lowToo.Set(route("bar", "low2:bar", "low2:2"))
+	high.Set(route("foo", "high:foo", "high:1"))
+
+	runAggregator(t, agg)
+
+	// the high priority provider wins
+	targets, ok := agg.URLToContainerByHostname("FOO.indocker.app")
+	require.True(t, ok)
+	assert.Equal(t, docker.ContainerMap{"high:foo": {Scheme: "http", Host: "high:1"}}, targets)
+
+	// the same priority providers are merged
+	targets, ok = agg.URLToContainerByHostname("bar")
+	require.True(t, ok)
+	assert.Len(t, targets, 2)
+
+	// the provider name is used as the route source
+	opts, ok := agg.RouteOptions("high:foo")
+	require.True(t, ok)
+	assert.Equal(t, docker.RouteSource("high"), opts.Source)
+
+	_, ok = agg.RouteOptions("low:foo")
+	assert.False(t, ok)
+
+	assert.Len(t, agg.AllContainerURLs(), 2)
+	assert.Empty(t, agg.RouteConflicts())
+}
+
+func TestAggregator_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p   = routing.NewMemoryProvider("mem")
+		agg = routing.NewAggregator(zap.NewNop()).Add(p, 0)
+	)
+
+	runAggregator(t, agg)
+
+	var sub, stop = agg.SubscribeForRoutingUpdates()
+	defer stop()
+
+	p.Set(route("foo", "mem:foo", "foo:1"))
+
+	select {
+	case routes := <-sub:
+		assert.Contains(t, routes, "foo")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	assert.True(t, p.Delete("foo"))
+	assert.False(t, p.Delete("foo"))
+
+	select {
+	case routes := <-sub:
+		assert.Empty(t, routes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestAggregator_RouteRemovedAt(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p   = routing.NewMemoryProvider("mem")
+		agg = routing.NewAggregator(zap.NewNop()).Add(p, 0)
+	)
+
+	p.Set(route("foo", "mem:foo", "foo:1"))
+
+	runAggregator(t, agg)
+
+	var sub, stop = agg.SubscribeForRoutingUpdates()
+	defer stop()
+
+	var wait = func() {
+		t.Helper()
+
+		select {
+		case <-sub:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	_, removed := agg.RouteRemovedAt("foo")
+	assert.False(t, removed) // routed
+
+	_, removed = agg.RouteRemovedAt("bar")
+	assert.False(t, removed) // never routed
+
+	var before = time.Now()
+
+	assert.True(t, p.Delete("foo"))
+	wait()
+
+	removedAt, removed := agg.RouteRemovedAt("FOO.indocker.app")
+	assert.True(t, removed)
+	assert.False(t, removedAt.Before(before))
+
+	p.Set(route("foo", "mem:foo", "foo:2")) // the route is back (e.g. the container is recreated)
+	wait()
+
+	_, removed = agg.RouteRemovedAt("foo")
+	assert.False(t, removed)
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) Watch(context.Context, func([]routing.Route)) error { return errors.New("boom") }
+
+func TestAggregator_RunError(t *testing.T) {
+	t.Parallel()
+
+	var err = routing.NewAggregator(zap.NewNop()).
+		Add(routing.NewMemoryProvider("mem"), 0).
+		Add(failingProvider{}, 0).
+		Run(context.Background())
+
+	assert.EqualError(t, err, "failing routes provider: boom")
+}
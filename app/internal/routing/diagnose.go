@@ -0,0 +1,45 @@
+package routing
+
+import (
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type routeResolver interface {
+	docker.RoutingURLResolver
+	docker.RouteOptionsResolver
+}
+
+// ExplainOverride checks if the container, routed by the docker state, is actually routed by the aggregated routing
+// table. If the hostname is taken by another provider (with the higher priority), the corresponding diagnostic step
+// is added, and the diagnosis is marked as not routed.
+func ExplainOverride(d *docker.Diagnosis, router routeResolver) {
+	if d == nil || !d.Routed {
+		return
+	}
+
+	targets, found := router.URLToContainerByHostname(d.Hostname)
+	if found {
+		if _, ok := targets[d.ContainerID]; ok {
+			return // the container is routed
+		}
+	}
+
+	var message = "the hostname \"" + d.Hostname + "\" is missing in the routing table"
+
+	for id := range targets {
+		if opts, ok := router.RouteOptions(id); ok && opts.Source != "" {
+			message = "the hostname \"" + d.Hostname + "\" is overridden by the route from the " +
+				string(opts.Source) + " provider"
+
+			break
+		}
+	}
+
+	d.Steps = append(d.Steps, docker.DiagnosticStep{
+		Name:    "route provider",
+		Level:   docker.DiagnosticError,
+		Message: message,
+	})
+
+	d.Routed, d.URL = false, nil
+}
@@ -0,0 +1,70 @@
+package routing
+
+import (
+	"context"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type (
+	dockerState interface {
+		docker.AllContainerURLsResolver
+		docker.RoutingUpdateSubscriber
+		docker.RouteOptionsResolver
+		docker.RouteConflictsResolver
+	}
+
+	// DockerProvider provides the routes, discovered using the docker labels.
+	DockerProvider struct{ state dockerState }
+)
+
+var (
+	_ Provider                      = (*DockerProvider)(nil) // verify interface implementation
+	_ docker.RouteConflictsResolver = (*DockerProvider)(nil) // --//--
+)
+
+// NewDockerProvider creates a new docker provider. The state must be updated by the caller (e.g. using the
+// [docker.State.StartAutoUpdate]).
+func NewDockerProvider(state dockerState) *DockerProvider { return &DockerProvider{state: state} }
+
+// Name returns the provider name.
+func (*DockerProvider) Name() string { return string(docker.RouteSourceDocker) }
+
+// RouteConflicts returns the route conflicts, detected by the docker state.
+func (p *DockerProvider) RouteConflicts() []docker.RouteConflict { return p.state.RouteConflicts() }
+
+// Watch implements the [Provider] interface.
+func (p *DockerProvider) Watch(ctx context.Context, update func([]Route)) error {
+	var sub, stop = p.state.SubscribeForRoutingUpdates()
+	defer stop()
+
+	update(p.toRoutes(p.state.AllContainerURLs()))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case routes, isOpened := <-sub:
+			if !isOpened {
+				return nil
+			}
+
+			update(p.toRoutes(routes))
+		}
+	}
+}
+
+// toRoutes converts the docker routing to the list of routes.
+func (p *DockerProvider) toRoutes(routes docker.RoutesMap) []Route {
+	var result = make([]Route, 0, len(routes))
+
+	for hostname, containers := range routes {
+		for id, u := range containers {
+			var opts, _ = p.state.RouteOptions(id)
+
+			result = append(result, Route{Hostname: hostname, TargetID: id, URL: u, Options: opts})
+		}
+	}
+
+	return result
+}
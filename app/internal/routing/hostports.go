@@ -0,0 +1,160 @@
+package routing
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type (
+	// Router is the routing table, used by the proxy and the routes API.
+	Router interface {
+		docker.RoutingURLResolver
+		docker.AllContainerURLsResolver
+		docker.RoutingUpdateSubscriber
+		docker.RouteOptionsResolver
+		docker.RouteConflictsResolver
+		docker.RemovedRouteResolver
+	}
+
+	// PortRange is an inclusive range of TCP ports.
+	PortRange struct{ From, To uint16 }
+
+	// PortRanges is a list of the port ranges.
+	PortRanges []PortRange
+
+	// HostPorts routes the hostnames like "3000" (and, optionally, "<name>-3000") to the port on the host, if the
+	// hostname is not routed by the underlying router. Only the allowed ports are routed.
+	HostPorts struct {
+		Router
+
+		address string     // the host address (e.g. the docker host gateway IP)
+		ports   PortRanges // allowed ports
+		named   bool       // allow the "<name>-<port>" hostnames
+	}
+
+	// HostPortsOption allows to change the [HostPorts] options.
+	HostPortsOption func(*HostPorts)
+)
+
+var _ Router = (*HostPorts)(nil) // verify interface implementation
+
+// hostPortTargetPrefix is the target ID prefix for the host port routes.
+const hostPortTargetPrefix = string(docker.RouteSourceHostPort) + ":"
+
+// ParsePortRanges parses the comma-separated list of ports and port ranges (e.g. "3000-3999,5173,8080").
+func ParsePortRanges(s string) (PortRanges, error) {
+	var ranges PortRanges
+
+	for part := range strings.SplitSeq(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		var from, to, isRange = strings.Cut(part, "-")
+
+		if !isRange {
+			to = from
+		}
+
+		f, fErr := strconv.ParseUint(strings.TrimSpace(from), 10, 16)
+		t, tErr := strconv.ParseUint(strings.TrimSpace(to), 10, 16)
+
+		if fErr != nil || tErr != nil || f == 0 || t < f {
+			return nil, fmt.Errorf("wrong port range: %q", part)
+		}
+
+		ranges = append(ranges, PortRange{From: uint16(f), To: uint16(t)})
+	}
+
+	return ranges, nil
+}
+
+// Contains returns true if the port is in any of the ranges.
+func (r PortRanges) Contains(port uint16) bool {
+	return slices.ContainsFunc(r, func(pr PortRange) bool { return port >= pr.From && port <= pr.To })
+}
+
+// String returns the ranges in the same format, as they are parsed.
+func (r PortRanges) String() string {
+	var parts = make([]string, len(r))
+
+	for i, pr := range r {
+		if pr.From == pr.To {
+			parts[i] = strconv.Itoa(int(pr.From))
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", pr.From, pr.To)
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// WithNamedHostPorts allows the "<name>-<port>" hostnames (e.g. "vite-5173").
+func WithNamedHostPorts(allow bool) HostPortsOption { return func(h *HostPorts) { h.named = allow } }
+
+// NewHostPorts wraps the router to route the port hostnames to the host address.
+func NewHostPorts(next Router, address string, ports PortRanges, opts ...HostPortsOption) *HostPorts {
+	var h = HostPorts{Router: next, address: address, ports: ports}
+
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	return &h
+}
+
+// hostPortRegex matches the "<port>" and "<name>-<port>" hostnames.
+//
+//nolint:gochecknoglobals
+var hostPortRegex = regexp.MustCompile(`^(?:([a-z0-9][a-z0-9-]*)-)?([0-9]{1,5})$`)
+
+// URLToContainerByHostname returns the route targets for the hostname. If the hostname is not routed by the
+// underlying router, and looks like the allowed port, the host port is returned.
+func (h *HostPorts) URLToContainerByHostname(hostname string) (docker.ContainerMap, bool) {
+	if targets, ok := h.Router.URLToContainerByHostname(hostname); ok {
+		return targets, ok
+	}
+
+	var port, ok = h.match(hostname)
+	if !ok {
+		return nil, false
+	}
+
+	var p = strconv.Itoa(int(port))
+
+	return docker.ContainerMap{
+		hostPortTargetPrefix + p: {Scheme: "http", Host: net.JoinHostPort(h.address, p)},
+	}, true
+}
+
+// RouteOptions returns the options of the route target with the given ID.
+func (h *HostPorts) RouteOptions(targetID string) (docker.RouteOptions, bool) {
+	if strings.HasPrefix(targetID, hostPortTargetPrefix) {
+		return docker.RouteOptions{Source: docker.RouteSourceHostPort}, true
+	}
+
+	return h.Router.RouteOptions(targetID)
+}
+
+// match extracts the allowed port from the hostname.
+func (h *HostPorts) match(hostname string) (uint16, bool) {
+	hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".indocker.app")
+
+	var m = hostPortRegex.FindStringSubmatch(hostname)
+	if m == nil || (m[1] != "" && !h.named) {
+		return 0, false
+	}
+
+	port, err := strconv.ParseUint(m[2], 10, 16)
+	if err != nil || port == 0 || !h.ports.Contains(uint16(port)) {
+		return 0, false
+	}
+
+	return uint16(port), true
+}
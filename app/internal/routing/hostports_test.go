@@ -0,0 +1,103 @@
+package routing_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+	"gh.tarampamp.am/indocker-app/app/internal/routing"
+)
+
+func TestParsePortRanges(t *testing.T) {
+	t.Parallel()
+
+	for name, tt := range map[string]struct {
+		give      string
+		want      routing.PortRanges
+		wantError bool
+	}{
+		"empty":          {give: "", want: nil},
+		"single":         {give: "5173", want: routing.PortRanges{{From: 5173, To: 5173}}},
+		"ranges":         {give: "3000-3999, 8080", want: routing.PortRanges{{From: 3000, To: 3999}, {From: 8080, To: 8080}}},
+		"reversed":       {give: "3999-3000", wantError: true},
+		"zero":           {give: "0", wantError: true},
+		"too big":        {give: "70000", wantError: true},
+		"not a number":   {give: "foo", wantError: true},
+		"trailing comma": {give: "80,", want: routing.PortRanges{{From: 80, To: 80}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := routing.ParsePortRanges(tt.give)
+
+			if tt.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHostPorts(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mem = routing.NewMemoryProvider("mem")
+		agg = routing.NewAggregator(zap.NewNop()).Add(mem, 0)
+	)
+
+	mem.Set(route("3001", "mem:3001", "container:80"))
+
+	runAggregator(t, agg)
+
+	var ranges, _ = routing.ParsePortRanges("3000-3999")
+
+	for name, tt := range map[string]struct {
+		giveNamed    bool
+		giveHostname string
+		wantURL      string // empty = not routed
+	}{
+		"port":                    {giveHostname: "3000.indocker.app", wantURL: "http://172.17.0.1:3000"},
+		"routed by the next":      {giveHostname: "3001", wantURL: "http://container:80"},
+		"not allowed port":        {giveHostname: "4000"},
+		"named (disabled)":        {giveHostname: "vite-3000"},
+		"named (enabled)":         {giveNamed: true, giveHostname: "vite-3000", wantURL: "http://172.17.0.1:3000"},
+		"named, not allowed port": {giveNamed: true, giveHostname: "vite-4000"},
+		"not a port":              {giveNamed: true, giveHostname: "vite"},
+		"too big port":            {giveHostname: "99999"},
+		"named with dashes":       {giveNamed: true, giveHostname: "my-app-3500", wantURL: "http://172.17.0.1:3500"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var h = routing.NewHostPorts(agg, "172.17.0.1", ranges, routing.WithNamedHostPorts(tt.giveNamed))
+
+			targets, ok := h.URLToContainerByHostname(tt.giveHostname)
+
+			if tt.wantURL == "" {
+				assert.False(t, ok)
+
+				return
+			}
+
+			require.True(t, ok)
+			require.Len(t, targets, 1)
+
+			for id, u := range targets {
+				assert.Equal(t, tt.wantURL, (&url.URL{Scheme: u.Scheme, Host: u.Host}).String())
+
+				if opts, found := h.RouteOptions(id); assert.True(t, found) && tt.giveHostname != "3001" {
+					assert.Equal(t, docker.RouteSourceHostPort, opts.Source)
+				}
+			}
+		})
+	}
+}
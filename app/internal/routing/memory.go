@@ -0,0 +1,97 @@
+package routing
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemoryProvider is a provider, which keeps the routes in memory. Routes can be added and removed at any time (e.g.
+// using the API).
+type MemoryProvider struct {
+	name string
+
+	mu      sync.Mutex
+	routes  map[string]Route // map[target_id]Route
+	changed chan struct{}    // is closed (and replaced) on every change
+}
+
+var _ Provider = (*MemoryProvider)(nil) // verify interface implementation
+
+// NewMemoryProvider creates a new in-memory provider with the given name.
+func NewMemoryProvider(name string) *MemoryProvider {
+	return &MemoryProvider{name: name, routes: make(map[string]Route), changed: make(chan struct{})}
+}
+
+// Name returns the provider name.
+func (p *MemoryProvider) Name() string { return p.name }
+
+// Set adds (or replaces) the route with the same target ID.
+func (p *MemoryProvider) Set(r Route) {
+	p.mu.Lock()
+	p.routes[r.TargetID] = r
+	p.notify()
+	p.mu.Unlock()
+}
+
+// Delete removes all routes with the given hostname. It returns false if nothing was removed.
+func (p *MemoryProvider) Delete(hostname string) (deleted bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, r := range p.routes {
+		if r.Hostname == hostname {
+			delete(p.routes, id)
+
+			deleted = true
+		}
+	}
+
+	if deleted {
+		p.notify()
+	}
+
+	return
+}
+
+// Routes returns all routes, sorted by the hostname and target ID.
+func (p *MemoryProvider) Routes() []Route {
+	p.mu.Lock()
+	var routes = slices.Collect(maps.Values(p.routes))
+	p.mu.Unlock()
+
+	slices.SortFunc(routes, func(a, b Route) int {
+		if c := strings.Compare(a.Hostname, b.Hostname); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.TargetID, b.TargetID)
+	})
+
+	return routes
+}
+
+// Watch implements the [Provider] interface.
+func (p *MemoryProvider) Watch(ctx context.Context, update func([]Route)) error {
+	for {
+		p.mu.Lock()
+		var changed = p.changed
+		p.mu.Unlock()
+
+		update(p.Routes())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+// notify wakes up the watchers. The mu must be locked by the caller.
+func (p *MemoryProvider) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
@@ -0,0 +1,30 @@
+// Package routing merges the routes from several sources (providers) into a single routing table.
+package routing
+
+import (
+	"context"
+	"net/url"
+
+	"gh.tarampamp.am/indocker-app/app/internal/docker"
+)
+
+type (
+	// Route is a single route target.
+	Route struct {
+		Hostname string  // the hostname (lowercase, without the ".indocker.app" suffix)
+		TargetID string  // the target ID (e.g. the container ID), must be unique across all providers
+		URL      url.URL // the upstream URL
+		Options  docker.RouteOptions
+	}
+
+	// Provider is a source of the routes. Any number of providers can be registered in the [Aggregator].
+	Provider interface {
+		// Name returns the provider name. It's used as the route source, if it's not set by the provider.
+		Name() string
+
+		// Watch watches for the routes, and calls the update function with the full routes snapshot (not the
+		// changes) every time the routes are changed. The first call must provide the initial snapshot (even if
+		// it's empty). Watch blocks until the context is canceled, or a fatal error occurs.
+		Watch(ctx context.Context, update func([]Route)) error
+	}
+)